@@ -0,0 +1,219 @@
+// Copyright © 2019 The Vultr-cli Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/vultr/govultr/v2"
+	"github.com/vultr/vultr-cli/v2/cmd/printer"
+)
+
+const (
+	backupDaily        = "daily"
+	backupWeekly       = "weekly"
+	backupMonthly      = "monthly"
+	backupDailyAltEven = "daily_alt_even"
+	backupDailyAltOdd  = "daily_alt_odd"
+)
+
+var backupPolicyApply = &cobra.Command{
+	Use:   "apply",
+	Short: "set one backup schedule on every instance with a tag",
+	Long: `apply sets the same backup schedule on every instance that matches --tag.
+
+Nothing is staggered by default: without --stagger every instance is backed up
+at --hour. With --stagger n the instances, sorted by label, are spread over n
+consecutive hours starting at --hour, so they are not all backed up at once.
+Instances that do not have automatic backups enabled are reported and left
+alone.`,
+	Example: `
+	# Back up production every night between 03:00 and 05:59 UTC
+	vultr-cli instance backup policy apply --tag prod --type daily --hour 3 --stagger 3
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		tag, _ := cmd.Flags().GetString("tag")
+		cronType, _ := cmd.Flags().GetString("type")
+		hour, _ := cmd.Flags().GetInt("hour")
+		dow, _ := cmd.Flags().GetInt("dow")
+		dom, _ := cmd.Flags().GetInt("dom")
+		stagger, _ := cmd.Flags().GetInt("stagger")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		if _, err := newBackupScheduleReq(cmd, cronType, hour, dow, dom); err != nil {
+			fmt.Printf("error applying backup policy : %v\n", err)
+			os.Exit(1)
+		}
+		if stagger < 1 || stagger > 24 {
+			fmt.Println("error applying backup policy : --stagger must be between 1 and 24")
+			os.Exit(1)
+		}
+
+		ctx := context.Background()
+		instances, err := selectInstances(ctx, tag)
+		if err != nil {
+			fmt.Printf("error listing instances : %v\n", err)
+			os.Exit(1)
+		}
+
+		// Sort so every run hands out the same hours
+		sort.SliceStable(instances, func(i, j int) bool {
+			return instances[i].Label < instances[j].Label
+		})
+
+		var results []printer.BackupPolicyResult
+		failed := false
+		for i := range instances {
+			instance := &instances[i]
+			req := &govultr.BackupScheduleReq{
+				Type: cronType,
+				Hour: govultr.IntToIntPtr((hour + i%stagger) % 24),
+				Dow:  govultr.IntToIntPtr(dow),
+				Dom:  dom,
+			}
+
+			result := printer.BackupPolicyResult{
+				InstanceID: instance.ID,
+				Label:      instance.Label,
+				Schedule:   describeBackupSchedule(req.Type, *req.Hour, dow, dom),
+			}
+
+			current, err := client.Instance.GetBackupSchedule(ctx, instance.ID)
+			switch {
+			case err != nil:
+				result.Action = fmt.Sprintf("error : %v", err)
+				failed = true
+			case current.Enabled == nil || !*current.Enabled:
+				result.Action = "backups disabled"
+			case backupScheduleMatches(current, req):
+				result.Action = "unchanged"
+			case dryRun:
+				result.Action = "update"
+			default:
+				result.Action = "updated"
+				if err := client.Instance.SetBackupSchedule(ctx, instance.ID, req); err != nil {
+					result.Action = fmt.Sprintf("error : %v", err)
+					failed = true
+				}
+			}
+
+			results = append(results, result)
+		}
+
+		printer.BackupPolicyResults(results)
+		if failed {
+			os.Exit(1)
+		}
+	},
+}
+
+// newBackupScheduleReq checks that the schedule flags fit the cron type
+// before they are sent, as the API accepts and ignores the ones that do not.
+func newBackupScheduleReq(cmd *cobra.Command, cronType string, hour, dow, dom int) (*govultr.BackupScheduleReq, error) {
+	changed := cmd.Flags().Changed
+
+	switch cronType {
+	case backupDaily, backupWeekly, backupMonthly, backupDailyAltEven, backupDailyAltOdd:
+	default:
+		return nil, fmt.Errorf("unknown backup type %q. Can be one of 'daily', 'weekly', 'monthly', 'daily_alt_even', or 'daily_alt_odd'", cronType)
+	}
+
+	if hour < 0 || hour > 23 {
+		return nil, errors.New("--hour must be between 0 and 23")
+	}
+
+	switch {
+	case cronType == backupWeekly && (dow < 0 || dow > 6):
+		return nil, errors.New("--dow must be between 0 and 6")
+	case cronType != backupWeekly && changed("dow"):
+		return nil, errors.New("--dow only applies to weekly backups")
+	}
+
+	switch {
+	case cronType == backupMonthly && !changed("dom"):
+		return nil, errors.New("monthly backups need a --dom")
+	case cronType == backupMonthly && (dom < 1 || dom > 28):
+		return nil, errors.New("--dom must be between 1 and 28")
+	case cronType != backupMonthly && changed("dom"):
+		return nil, errors.New("--dom only applies to monthly backups")
+	}
+
+	return &govultr.BackupScheduleReq{
+		Type: cronType,
+		Hour: govultr.IntToIntPtr(hour),
+		Dow:  govultr.IntToIntPtr(dow),
+		Dom:  dom,
+	}, nil
+}
+
+// backupScheduleMatches reports whether an instance already has the schedule
+func backupScheduleMatches(current *govultr.BackupSchedule, req *govultr.BackupScheduleReq) bool {
+	if current.Type != req.Type || current.Hour != *req.Hour {
+		return false
+	}
+
+	switch req.Type {
+	case backupWeekly:
+		return current.Dow == *req.Dow
+	case backupMonthly:
+		return current.Dom == req.Dom
+	default:
+		return true
+	}
+}
+
+// describeBackupSchedule spells out a backup schedule, such as
+// "every Monday at 03:00 UTC"
+func describeBackupSchedule(cronType string, hour, dow, dom int) string {
+	at := fmt.Sprintf("at %02d:00 UTC", hour)
+
+	switch cronType {
+	case backupDaily:
+		return "every day " + at
+	case backupDailyAltEven:
+		return "every even day of the month " + at
+	case backupDailyAltOdd:
+		return "every odd day of the month " + at
+	case backupWeekly:
+		return fmt.Sprintf("every %s %s", time.Weekday(dow), at)
+	case backupMonthly:
+		return fmt.Sprintf("on day %d of every month %s", dom, at)
+	default:
+		return cronType
+	}
+}
+
+// backupNextRun converts the next scheduled backup to local time
+func backupNextRun(b *govultr.BackupSchedule) string {
+	if b.NextScheduleTimeUTC == "" {
+		return ""
+	}
+
+	for _, layout := range []string{"2006-01-02 15:04:05", time.RFC3339} {
+		t, err := time.ParseInLocation(layout, b.NextScheduleTimeUTC, time.UTC)
+		if err == nil {
+			return t.Local().Format("2006-01-02 15:04 MST")
+		}
+	}
+
+	// Show what the API sent rather than nothing
+	return b.NextScheduleTimeUTC + " UTC"
+}
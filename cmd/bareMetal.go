@@ -16,7 +16,6 @@ package cmd
 
 import (
 	"context"
-	"encoding/base64"
 	"errors"
 	"fmt"
 	"os"
@@ -50,6 +49,7 @@ func BareMetal() *cobra.Command {
 		BareMetalOS(),
 		bareMetalReboot,
 		bareMetalReinstall,
+		bareMetalSSH,
 		BareMetalUserData(),
 	)
 
@@ -67,12 +67,15 @@ func BareMetal() *cobra.Command {
 	bareMetalCreate.Flags().IntP("app", "a", 0, "(optional) ID of the application that will be installed on the server.")
 	bareMetalCreate.Flags().StringP("image", "", "", "(optional) Image ID of the application that will be installed on the server.")
 	bareMetalCreate.Flags().StringP("userdata", "u", "", "(optional) A generic data store, which some provisioning tools and cloud operating systems use as a configuration file.")
+	bareMetalCreate.Flags().StringArrayP("userdata-file", "", []string{}, "(optional) File to read user-data from, rendered as a Go template. Can be given several times.")
 	bareMetalCreate.Flags().StringP("notify", "n", "", "(optional) Whether an activation email will be sent when the server is ready. Possible values: 'yes', 'no'. Defaults to 'yes'.")
 	bareMetalCreate.Flags().StringP("hostname", "m", "", "(optional) The hostname to assign to the server.")
 	bareMetalCreate.Flags().StringP("tag", "t", "", "(optional) The tag to assign to the server.")
 	bareMetalCreate.Flags().StringP("ripv4", "v", "", "(optional) IP address of the floating IP to use as the main IP of this server.")
 	bareMetalCreate.Flags().BoolP("persistent_pxe", "x", false, "enable persistent_pxe | true or false")
 
+	sshFlags(bareMetalSSH)
+
 	bareMetalList.Flags().StringP("cursor", "c", "", "(optional) Cursor for paging.")
 	bareMetalList.Flags().IntP("per-page", "p", 100, "(optional) Number of items requested per page. Default is 100 and Max is 500.")
 
@@ -106,6 +109,7 @@ var bareMetalCreate = &cobra.Command{
 		ripv4, _ := cmd.Flags().GetString("ripv4")
 		pxe, _ := cmd.Flags().GetBool("persistent_pxe")
 		image, _ := cmd.Flags().GetString("image")
+		userdataFiles, _ := cmd.Flags().GetStringArray("userdata-file")
 
 		options := &govultr.BareMetalCreate{
 			StartupScriptID: script,
@@ -123,9 +127,13 @@ var bareMetalCreate = &cobra.Command{
 			PersistentPxe:   govultr.BoolToBoolPtr(pxe),
 		}
 
-		if userdata != "" {
-			options.UserData = base64.StdEncoding.EncodeToString([]byte(userdata))
+		data := &instanceTemplateData{Index: 1, Count: 1, Region: region, Plan: plan, Label: label, Hostname: hostname, Tag: tag}
+		userData, err := userDataFromFiles(userdata, userdataFiles, data)
+		if err != nil {
+			fmt.Printf("error reading user-data : %v\n", err)
+			os.Exit(1)
 		}
+		options.UserData = userData
 
 		if notify == "yes" {
 			options.ActivationEmail = govultr.BoolToBoolPtr(true)
@@ -36,7 +36,7 @@ func BareMetalUserData() *cobra.Command {
 	}
 
 	bareMetalSetUserData.Flags().StringP("userdata", "d", "/dev/stdin", "file to read userdata from")
-	bareMetalUserDataCmd.AddCommand(bareMetalGetUserData, bareMetalSetUserData)
+	bareMetalUserDataCmd.AddCommand(bareMetalGetUserData, bareMetalSetUserData, bareMetalDiffUserData, bareMetalEditUserData)
 
 	return bareMetalUserDataCmd
 }
@@ -93,3 +93,85 @@ var bareMetalSetUserData = &cobra.Command{
 		fmt.Println("Set user-data for bare metal")
 	},
 }
+
+var bareMetalDiffUserData = &cobra.Command{
+	Use:   "diff <bareMetalID> <file>",
+	Short: "Compare the user-data of a bare metal server with a local file.",
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 2 {
+			return errors.New("please provide a bareMetalID and a file")
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		u, err := client.BareMetalServer.GetUserData(context.TODO(), args[0])
+		if err != nil {
+			fmt.Printf("%v\n", err)
+			os.Exit(1)
+		}
+
+		current, err := decodeUserData(u)
+		if err != nil {
+			fmt.Printf("%v\n", err)
+			os.Exit(1)
+		}
+
+		diff, err := diffUserData(current, args[1])
+		if err != nil {
+			fmt.Printf("error reading user-data : %v\n", err)
+			os.Exit(1)
+		}
+
+		// Exit like diff(1) so the command can be used in scripts
+		if diff != "" {
+			fmt.Print(diff)
+			os.Exit(1)
+		}
+	},
+}
+
+var bareMetalEditUserData = &cobra.Command{
+	Use:   "edit <bareMetalID>",
+	Short: "Edit the user-data of a bare metal server in $EDITOR.",
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 {
+			return errors.New("please provide a bareMetalID")
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		u, err := client.BareMetalServer.GetUserData(context.TODO(), args[0])
+		if err != nil {
+			fmt.Printf("%v\n", err)
+			os.Exit(1)
+		}
+
+		current, err := decodeUserData(u)
+		if err != nil {
+			fmt.Printf("%v\n", err)
+			os.Exit(1)
+		}
+
+		edited, changed, err := editUserData(current)
+		if err != nil {
+			fmt.Printf("error editing user-data : %v\n", err)
+			os.Exit(1)
+		}
+
+		if !changed {
+			fmt.Println("User-data unchanged")
+			return
+		}
+
+		options := &govultr.BareMetalUpdate{
+			UserData: encodeUserData(string(edited)),
+		}
+
+		if _, err := client.BareMetalServer.Update(context.TODO(), args[0], options); err != nil {
+			fmt.Printf("error setting user-data : %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Println("Set user-data for bare metal")
+	},
+}
@@ -0,0 +1,72 @@
+// Copyright © 2019 The Vultr-cli Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// stdin is shared by every prompt. A reader per prompt would buffer ahead
+// and swallow the answers to later prompts when input is piped.
+var stdin = bufio.NewReader(os.Stdin)
+
+// confirm asks a yes/no question on the terminal. Anything but y or yes,
+// including no input at all, is a no.
+func confirm(prompt string) bool {
+	fmt.Printf("%s [y/N]: ", prompt)
+
+	answer, err := stdin.ReadString('\n')
+	if err != nil && answer == "" {
+		fmt.Println()
+		return false
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	default:
+		return false
+	}
+}
+
+// choose asks for a number between 1 and n and returns it as an index. An
+// empty answer cancels.
+func choose(prompt string, n int) (int, bool) {
+	for {
+		fmt.Printf("%s [1-%d]: ", prompt, n)
+
+		answer, err := stdin.ReadString('\n')
+		answer = strings.TrimSpace(answer)
+		if answer == "" {
+			if err != nil {
+				fmt.Println()
+			}
+			return 0, false
+		}
+
+		i, convErr := strconv.Atoi(answer)
+		if convErr == nil && i >= 1 && i <= n {
+			return i - 1, true
+		}
+		if err != nil {
+			return 0, false
+		}
+		fmt.Printf("Please enter a number between 1 and %d\n", n)
+	}
+}
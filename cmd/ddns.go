@@ -0,0 +1,306 @@
+// Copyright © 2019 The Vultr-cli Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/vultr/govultr/v2"
+)
+
+const ddnsLookupTimeout = 10 * time.Second
+
+var dnsDDNS = &cobra.Command{
+	Use:   "ddns",
+	Short: "keep A and AAAA records pointed at this machine's public address",
+	Long: `ddns looks up the public address of this machine every --interval and points
+the A record, and with --ipv6 the AAAA record, of --name in --domain at it.
+
+The address comes from --ipv4-url and --ipv6-url, which must answer with the
+address of the caller as plain text, or from the addresses of --interface.
+Records are only written when the address changes. ddns runs in the foreground
+and logs to stderr, so it can run as a systemd service; --once does a single
+update and exits, for cron.`,
+	Example: `
+	vultr-cli dns ddns --domain example.com --name home --interval 5m --ipv6
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		domain, _ := cmd.Flags().GetString("domain")
+		name, _ := cmd.Flags().GetString("name")
+		interval, _ := cmd.Flags().GetDuration("interval")
+		ipv4, _ := cmd.Flags().GetBool("ipv4")
+		ipv6, _ := cmd.Flags().GetBool("ipv6")
+		ipv4URL, _ := cmd.Flags().GetString("ipv4-url")
+		ipv6URL, _ := cmd.Flags().GetString("ipv6-url")
+		iface, _ := cmd.Flags().GetString("interface")
+		ttl, _ := cmd.Flags().GetInt("ttl")
+		once, _ := cmd.Flags().GetBool("once")
+
+		if !ipv4 && !ipv6 {
+			fmt.Println("error running ddns : at least one of --ipv4 and --ipv6 must be enabled")
+			os.Exit(1)
+		}
+		if !once && interval <= 0 {
+			fmt.Println("error running ddns : --interval must be greater than zero")
+			os.Exit(1)
+		}
+		if name == "@" {
+			name = ""
+		}
+
+		u := &ddnsUpdater{domain: domain, name: name, ttl: ttl, last: map[string]string{}}
+		if ipv4 {
+			u.families = append(u.families, ddnsFamily{recordType: "A", network: "tcp4", url: ipv4URL, iface: iface})
+		}
+		if ipv6 {
+			u.families = append(u.families, ddnsFamily{recordType: "AAAA", network: "tcp6", url: ipv6URL, iface: iface})
+		}
+
+		if once {
+			if !u.run(context.Background()) {
+				os.Exit(1)
+			}
+			return
+		}
+
+		log.Printf("updating %s every %s", displayFQDN(name, domain), interval)
+
+		stop := make(chan os.Signal, 1)
+		signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		u.run(context.Background())
+		for {
+			select {
+			case s := <-stop:
+				log.Printf("received %s, exiting", s)
+				return
+			case <-ticker.C:
+				u.run(context.Background())
+			}
+		}
+	},
+}
+
+// ddnsFamily is where the address of one record type comes from
+type ddnsFamily struct {
+	recordType string
+	network    string
+	url        string
+	iface      string
+}
+
+// ddnsUpdater remembers the addresses it has written so the API is only
+// called when one changes
+type ddnsUpdater struct {
+	domain   string
+	name     string
+	ttl      int
+	families []ddnsFamily
+	last     map[string]string
+}
+
+// run updates every record type once and reports whether all of them
+// succeeded. Errors are logged and retried on the next run.
+func (u *ddnsUpdater) run(ctx context.Context) bool {
+	ok := true
+	for _, f := range u.families {
+		ip, err := f.lookup(ctx)
+		if err != nil {
+			log.Printf("error looking up %s address : %v", f.recordType, err)
+			ok = false
+			continue
+		}
+
+		if u.last[f.recordType] == ip {
+			continue
+		}
+
+		changed, previous, err := u.upsert(ctx, f.recordType, ip)
+		if err != nil {
+			log.Printf("error updating %s record of %s : %v", f.recordType, displayFQDN(u.name, u.domain), err)
+			ok = false
+			continue
+		}
+
+		if changed {
+			if previous == "" {
+				log.Printf("created %s %s %s", displayFQDN(u.name, u.domain), f.recordType, ip)
+			} else {
+				log.Printf("updated %s %s %s -> %s", displayFQDN(u.name, u.domain), f.recordType, previous, ip)
+			}
+		} else if u.last[f.recordType] == "" {
+			log.Printf("%s %s is up to date at %s", displayFQDN(u.name, u.domain), f.recordType, ip)
+		}
+		u.last[f.recordType] = ip
+	}
+	return ok
+}
+
+// upsert points the record at ip, creating it when there is none. It returns
+// whether anything was written and the address the record had before.
+func (u *ddnsUpdater) upsert(ctx context.Context, recordType, ip string) (bool, string, error) {
+	records, err := listAllDomainRecords(ctx, u.domain)
+	if err != nil {
+		return false, "", err
+	}
+
+	var matches []govultr.DomainRecord
+	for _, r := range records {
+		if r.Type == recordType && canonicalName(r.Name) == canonicalName(u.name) {
+			matches = append(matches, r)
+		}
+	}
+
+	if len(matches) == 0 {
+		req := &govultr.DomainRecordReq{Name: u.name, Type: recordType, Data: ip, TTL: u.ttl}
+		if _, err := client.DomainRecord.Create(ctx, u.domain, req); err != nil {
+			return false, "", err
+		}
+		return true, "", nil
+	}
+
+	if len(matches) > 1 {
+		log.Printf("warning : %s has %d %s records, only %s is updated", displayFQDN(u.name, u.domain), len(matches), recordType, matches[0].ID)
+	}
+
+	current := matches[0]
+	if sameIP(current.Data, ip) {
+		return false, current.Data, nil
+	}
+
+	req := &govultr.DomainRecordReq{Name: current.Name, Data: ip, TTL: u.ttl}
+	if err := client.DomainRecord.Update(ctx, u.domain, current.ID, req); err != nil {
+		return false, current.Data, err
+	}
+	return true, current.Data, nil
+}
+
+// lookup finds the current address, from the interface when one is set and
+// from the resolver URL otherwise
+func (f ddnsFamily) lookup(ctx context.Context) (string, error) {
+	if f.iface != "" {
+		return interfaceAddress(f.iface, f.recordType == "AAAA")
+	}
+	return f.lookupURL(ctx)
+}
+
+// lookupURL asks a web service for the address it sees. The connection is
+// forced onto the family so an IPv4 lookup never answers with an IPv6
+// address and the other way round.
+func (f ddnsFamily) lookupURL(ctx context.Context) (string, error) {
+	dialer := &net.Dialer{Timeout: ddnsLookupTimeout}
+	httpClient := &http.Client{
+		Timeout: ddnsLookupTimeout,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, addr string) (net.Conn, error) {
+				return dialer.DialContext(ctx, f.network, addr)
+			},
+		},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, f.url, nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s answered %s", f.url, resp.Status)
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+	if err != nil {
+		return "", err
+	}
+
+	ip := net.ParseIP(strings.TrimSpace(string(body)))
+	if ip == nil || (ip.To4() == nil) != (f.recordType == "AAAA") {
+		return "", fmt.Errorf("%s did not answer with an %s address", f.url, f.recordType)
+	}
+	return ip.String(), nil
+}
+
+// interfaceAddress returns the first global address of a family on a local
+// interface, skipping private and link-local ones
+func interfaceAddress(name string, v6 bool) (string, error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return "", err
+	}
+
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return "", err
+	}
+
+	for _, a := range addrs {
+		ipNet, ok := a.(*net.IPNet)
+		if !ok {
+			continue
+		}
+
+		ip := ipNet.IP
+		if (ip.To4() == nil) != v6 || !ip.IsGlobalUnicast() || isPrivateIP(ip) {
+			continue
+		}
+		return ip.String(), nil
+	}
+
+	family := "IPv4"
+	if v6 {
+		family = "IPv6"
+	}
+	return "", fmt.Errorf("%s has no public %s address", name, family)
+}
+
+// isPrivateIP reports RFC 1918, shared address space and unique local
+// addresses
+func isPrivateIP(ip net.IP) bool {
+	for _, cidr := range []string{"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "100.64.0.0/10", "fc00::/7"} {
+		_, block, _ := net.ParseCIDR(cidr)
+		if block.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// displayFQDN shows a record name with its domain
+func displayFQDN(name, domain string) string {
+	if name == "" {
+		return domain
+	}
+	return name + "." + domain
+}
@@ -15,7 +15,12 @@
 package cmd
 
 import (
+	"context"
+	"strings"
+	"time"
+
 	"github.com/spf13/cobra"
+	"github.com/vultr/govultr/v2"
 )
 
 // DNS represents the dns command
@@ -28,5 +33,93 @@ func DNS() *cobra.Command {
 
 	dnsCmd.AddCommand(DNSDomain())
 	dnsCmd.AddCommand(DNSRecord())
+	dnsCmd.AddCommand(DNSACME())
+	dnsCmd.AddCommand(dnsSearch)
+	dnsCmd.AddCommand(dnsAudit)
+	dnsCmd.AddCommand(dnsDDNS)
+
+	// DDNS
+	dnsDDNS.Flags().StringP("domain", "m", "", "name of the domain the record is in")
+	dnsDDNS.MarkFlagRequired("domain")
+	dnsDDNS.Flags().StringP("name", "n", "", "name of the record, empty or @ for the domain itself")
+	dnsDDNS.Flags().DurationP("interval", "i", 5*time.Minute, "(optional) how often to look up the address")
+	dnsDDNS.Flags().Bool("ipv4", true, "(optional) keep the A record up to date")
+	dnsDDNS.Flags().Bool("ipv6", false, "(optional) keep the AAAA record up to date")
+	dnsDDNS.Flags().String("ipv4-url", "https://api.ipify.org", "(optional) URL that answers with the caller's IPv4 address")
+	dnsDDNS.Flags().String("ipv6-url", "https://api6.ipify.org", "(optional) URL that answers with the caller's IPv6 address")
+	dnsDDNS.Flags().String("interface", "", "(optional) read the address from this local interface instead of a URL")
+	dnsDDNS.Flags().Int("ttl", 300, "(optional) time to live for the records")
+	dnsDDNS.Flags().Bool("once", false, "(optional) update once and exit")
+
+	// Audit
+	dnsAudit.Flags().BoolP("all", "a", false, "(optional) also list the records that are fine")
+	dnsAudit.Flags().Bool("fix", false, "(optional) delete unowned and dangling records after confirmation")
+	dnsAudit.Flags().BoolP("yes", "y", false, "(optional) do not ask before deleting with --fix")
+
 	return dnsCmd
 }
+
+// listAllDomains walks every page of the domain list
+func listAllDomains(ctx context.Context) ([]govultr.Domain, error) {
+	var all []govultr.Domain
+	options := &govultr.ListOptions{PerPage: 100}
+	for {
+		domains, meta, err := client.Domain.List(ctx, options)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, domains...)
+
+		if meta == nil || meta.Links == nil || meta.Links.Next == "" {
+			return all, nil
+		}
+		options.Cursor = meta.Links.Next
+	}
+}
+
+// listAllDomainRecords walks every page of a domain's records
+func listAllDomainRecords(ctx context.Context, domain string) ([]govultr.DomainRecord, error) {
+	var all []govultr.DomainRecord
+	options := &govultr.ListOptions{PerPage: 100}
+	for {
+		records, meta, err := client.DomainRecord.List(ctx, domain, options)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, records...)
+
+		if meta == nil || meta.Links == nil || meta.Links.Next == "" {
+			return all, nil
+		}
+		options.Cursor = meta.Links.Next
+	}
+}
+
+// canonicalName lower cases a DNS name and drops the trailing dot
+func canonicalName(name string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(name)), ".")
+}
+
+// recordFQDN returns the full name of a record, which the API gives relative
+// to the domain with an empty name for the apex.
+func recordFQDN(domain, name string) string {
+	if name == "" || name == "@" {
+		return canonicalName(domain)
+	}
+	return canonicalName(name + "." + domain)
+}
+
+// zoneForName picks the domain with the longest matching suffix of name, or
+// an empty string when none of them contain it.
+func zoneForName(name string, domains []string) string {
+	name = canonicalName(name)
+
+	zone := ""
+	for _, d := range domains {
+		d = canonicalName(d)
+		if (name == d || strings.HasSuffix(name, "."+d)) && len(d) > len(zone) {
+			zone = d
+		}
+	}
+	return zone
+}
@@ -0,0 +1,288 @@
+// Copyright © 2019 The Vultr-cli Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/vultr/govultr/v2"
+)
+
+const acmeChallengePrefix = "_acme-challenge."
+
+// vultrNameservers answer for a domain that has no NS records of its own
+var vultrNameservers = []string{"ns1.vultr.com", "ns2.vultr.com"}
+
+// DNSACME represents the dns acme command
+func DNSACME() *cobra.Command {
+	acmeCmd := &cobra.Command{
+		Use:   "acme",
+		Short: "hooks for ACME DNS-01 challenges",
+		Long: `acme creates and removes the TXT records of ACME DNS-01 challenges, so
+certificates, including wildcards, can be issued for domains hosted on Vultr.
+
+The challenge name and value are taken from, in order, --fqdn and --value, the
+two arguments after the command as lego's exec provider passes them, or
+certbot's CERTBOT_DOMAIN and CERTBOT_VALIDATION environment variables. The
+record goes into the domain on the account with the longest matching suffix.
+
+	certbot certonly --manual --preferred-challenges dns \
+		--manual-auth-hook "vultr-cli dns acme present --wait" \
+		--manual-cleanup-hook "vultr-cli dns acme cleanup" \
+		-d example.com -d '*.example.com'`,
+	}
+
+	acmeCmd.AddCommand(acmePresent, acmeCleanup)
+
+	for _, c := range []*cobra.Command{acmePresent, acmeCleanup} {
+		c.Flags().String("fqdn", "", "name of the challenge record, such as _acme-challenge.example.com")
+		c.Flags().String("value", "", "value of the challenge record")
+	}
+
+	// Present
+	acmePresent.Flags().Int("ttl", 120, "(optional) time to live for the record")
+	acmePresent.Flags().BoolP("wait", "w", false, "(optional) wait until every nameserver of the domain serves the record")
+	acmePresent.Flags().Duration("timeout", 5*time.Minute, "(optional) how long to wait for the nameservers")
+
+	return acmeCmd
+}
+
+var acmePresent = &cobra.Command{
+	Use:   "present [fqdn] [value]",
+	Short: "create the TXT record of a challenge",
+	Example: `
+	vultr-cli dns acme present --fqdn _acme-challenge.www.example.com --value TOKEN --wait
+	`,
+	Args: cobra.MaximumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		fqdn, value, err := acmeChallenge(cmd, args)
+		if err != nil {
+			fmt.Printf("error reading acme challenge : %v\n", err)
+			os.Exit(1)
+		}
+		ttl, _ := cmd.Flags().GetInt("ttl")
+		wait, _ := cmd.Flags().GetBool("wait")
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+
+		ctx := context.Background()
+		domain, name, err := acmeZone(ctx, fqdn)
+		if err != nil {
+			fmt.Printf("error finding domain for %s : %v\n", fqdn, err)
+			os.Exit(1)
+		}
+
+		data, err := normalizeRecordData("TXT", value)
+		if err != nil {
+			fmt.Printf("error reading acme challenge : %v\n", err)
+			os.Exit(1)
+		}
+
+		records, err := listAllDomainRecords(ctx, domain)
+		if err != nil {
+			fmt.Printf("error while getting dns records : %v\n", err)
+			os.Exit(1)
+		}
+
+		// Other values can share the name, for example the apex and the
+		// wildcard of one certificate, so those are left alone
+		if len(acmeRecords(records, name, data)) == 0 {
+			req := &govultr.DomainRecordReq{Name: name, Type: "TXT", Data: data, TTL: ttl}
+			if _, err := client.DomainRecord.Create(ctx, domain, req); err != nil {
+				fmt.Printf("error while creating dns record : %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		if wait {
+			nameservers := acmeNameservers(records)
+			what := fmt.Sprintf("%s to be served by %s", fqdn, strings.Join(nameservers, ", "))
+			err := waitFor(what, timeout, func() (bool, error) {
+				return txtServed(ctx, fqdn, value, nameservers), nil
+			})
+			if err != nil {
+				fmt.Printf("error waiting for dns record : %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		fmt.Printf("created TXT record %s in %s\n", fqdn, domain)
+	},
+}
+
+var acmeCleanup = &cobra.Command{
+	Use:   "cleanup [fqdn] [value]",
+	Short: "delete the TXT record of a challenge",
+	Example: `
+	vultr-cli dns acme cleanup --fqdn _acme-challenge.www.example.com --value TOKEN
+	`,
+	Args: cobra.MaximumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		fqdn, value, err := acmeChallenge(cmd, args)
+		if err != nil {
+			fmt.Printf("error reading acme challenge : %v\n", err)
+			os.Exit(1)
+		}
+
+		ctx := context.Background()
+		domain, name, err := acmeZone(ctx, fqdn)
+		if err != nil {
+			fmt.Printf("error finding domain for %s : %v\n", fqdn, err)
+			os.Exit(1)
+		}
+
+		data, err := normalizeRecordData("TXT", value)
+		if err != nil {
+			fmt.Printf("error reading acme challenge : %v\n", err)
+			os.Exit(1)
+		}
+
+		records, err := listAllDomainRecords(ctx, domain)
+		if err != nil {
+			fmt.Printf("error while getting dns records : %v\n", err)
+			os.Exit(1)
+		}
+
+		for _, r := range acmeRecords(records, name, data) {
+			if err := client.DomainRecord.Delete(ctx, domain, r.ID); err != nil {
+				fmt.Printf("error while deleting dns record : %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		fmt.Printf("deleted TXT record %s in %s\n", fqdn, domain)
+	},
+}
+
+// acmeChallenge reads the challenge name and value from the flags, the
+// arguments or certbot's environment, in that order
+func acmeChallenge(cmd *cobra.Command, args []string) (string, string, error) {
+	fqdn, _ := cmd.Flags().GetString("fqdn")
+	value, _ := cmd.Flags().GetString("value")
+
+	if fqdn == "" && len(args) > 0 {
+		fqdn = args[0]
+	}
+	if value == "" && len(args) > 1 {
+		value = args[1]
+	}
+
+	if fqdn == "" {
+		if domain := os.Getenv("CERTBOT_DOMAIN"); domain != "" {
+			fqdn = acmeChallengePrefix + strings.TrimPrefix(domain, "*.")
+		}
+	}
+	if value == "" {
+		value = os.Getenv("CERTBOT_VALIDATION")
+	}
+
+	if fqdn == "" {
+		return "", "", errors.New("no challenge name, set --fqdn or CERTBOT_DOMAIN")
+	}
+	if value == "" {
+		return "", "", errors.New("no challenge value, set --value or CERTBOT_VALIDATION")
+	}
+
+	return canonicalName(fqdn), value, nil
+}
+
+// acmeZone finds the domain on the account the challenge belongs in and the
+// record name relative to it
+func acmeZone(ctx context.Context, fqdn string) (string, string, error) {
+	domains, err := listAllDomains(ctx)
+	if err != nil {
+		return "", "", err
+	}
+
+	names := make([]string, 0, len(domains))
+	for _, d := range domains {
+		names = append(names, d.Domain)
+	}
+
+	zone := zoneForName(fqdn, names)
+	if zone == "" {
+		return "", "", errors.New("no domain on the account contains it")
+	}
+
+	name, _ := relativeName(fqdn, zone)
+	return zone, name, nil
+}
+
+// acmeRecords returns the TXT records with the challenge name and value
+func acmeRecords(records []govultr.DomainRecord, name, data string) []govultr.DomainRecord {
+	var matches []govultr.DomainRecord
+	for _, r := range records {
+		if r.Type == "TXT" && canonicalName(r.Name) == canonicalName(name) && comparableData(r) == data {
+			matches = append(matches, r)
+		}
+	}
+	return matches
+}
+
+// acmeNameservers returns the nameservers in the apex NS records of a domain,
+// falling back to Vultr's when it has none
+func acmeNameservers(records []govultr.DomainRecord) []string {
+	var nameservers []string
+	for _, r := range records {
+		if r.Type == "NS" && r.Name == "" {
+			nameservers = append(nameservers, canonicalName(r.Data))
+		}
+	}
+	if len(nameservers) == 0 {
+		return vultrNameservers
+	}
+	return nameservers
+}
+
+// txtServed reports whether every nameserver answers the TXT query for fqdn
+// with value among the answers
+func txtServed(ctx context.Context, fqdn, value string, nameservers []string) bool {
+	for _, ns := range nameservers {
+		values, err := lookupTXTAt(ctx, fqdn, ns)
+		if err != nil {
+			return false
+		}
+
+		found := false
+		for _, v := range values {
+			if v == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// lookupTXTAt asks one nameserver directly, skipping any caches in between
+func lookupTXTAt(ctx context.Context, fqdn, nameserver string) ([]string, error) {
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	resolver := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, network, net.JoinHostPort(nameserver, "53"))
+		},
+	}
+	return resolver.LookupTXT(ctx, fqdn+".")
+}
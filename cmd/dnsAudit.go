@@ -0,0 +1,408 @@
+// Copyright © 2019 The Vultr-cli Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/vultr/govultr/v2"
+	"github.com/vultr/vultr-cli/v2/cmd/printer"
+)
+
+const (
+	auditOK           = "ok"
+	auditUnowned      = "unowned"
+	auditDangling     = "dangling"
+	auditExternal     = "external"
+	auditDependsOn    = "depends-on"
+	auditLookupFailed = "lookup-failed"
+
+	// auditMaxChain is how many CNAMEs are followed before giving up
+	auditMaxChain = 8
+)
+
+var dnsAudit = &cobra.Command{
+	Use:   "audit",
+	Short: "find records that point at addresses the account does not own",
+	Long: `audit collects every address of the account's instances, bare metal servers,
+reserved IPs and load balancers and checks the A, AAAA and CNAME records of all
+domains against them.
+
+Statuses are unowned (the record points at an address the account does not
+own), dangling (a CNAME whose target does not resolve), external (a CNAME that
+resolves outside the account, for example to a CDN), depends-on (a CNAME into
+the account's own domains that ends at an unowned A or AAAA record, which is
+named in the detail) and lookup-failed (the target could not be looked up right
+now). Records that are unowned or dangling can be taken over by whoever gets
+the address or name next; --fix deletes them after asking. External and
+depends-on records are only reported, the latter being fixed by fixing the
+record they depend on.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) > 0 {
+			return errors.New("audit checks every domain and takes no arguments")
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		all, _ := cmd.Flags().GetBool("all")
+		fix, _ := cmd.Flags().GetBool("fix")
+		yes, _ := cmd.Flags().GetBool("yes")
+
+		ctx := context.Background()
+		owned, err := ownedAddresses(ctx)
+		if err != nil {
+			fmt.Printf("error collecting account addresses : %v\n", err)
+			os.Exit(1)
+		}
+
+		domains, err := listAllDomains(ctx)
+		if err != nil {
+			fmt.Printf("error while getting domains : %v\n", err)
+			os.Exit(1)
+		}
+
+		zones := make([]string, 0, len(domains))
+		records := map[string][]govultr.DomainRecord{}
+		byName := map[string][]govultr.DomainRecord{}
+		for _, d := range domains {
+			zones = append(zones, d.Domain)
+
+			list, err := listAllDomainRecords(ctx, d.Domain)
+			if err != nil {
+				fmt.Printf("error while getting dns records of %s : %v\n", d.Domain, err)
+				os.Exit(1)
+			}
+			records[d.Domain] = list
+			for _, r := range list {
+				name := recordFQDN(d.Domain, r.Name)
+				byName[name] = append(byName[name], r)
+			}
+		}
+
+		a := &auditor{ctx: ctx, owned: owned, zones: zones, byName: byName}
+
+		var results []printer.DNSAuditResult
+		var fixable []printer.DNSAuditResult
+		for _, d := range domains {
+			for _, r := range records[d.Domain] {
+				if r.Type != "A" && r.Type != "AAAA" && r.Type != "CNAME" {
+					continue
+				}
+
+				status, detail := a.check(r)
+				result := printer.DNSAuditResult{
+					Domain: d.Domain,
+					ID:     r.ID,
+					Type:   r.Type,
+					Name:   recordFQDN(d.Domain, r.Name),
+					Data:   r.Data,
+					Status: status,
+					Detail: detail,
+				}
+
+				if all || status != auditOK {
+					results = append(results, result)
+				}
+				if status == auditUnowned || status == auditDangling {
+					fixable = append(fixable, result)
+				}
+			}
+		}
+
+		printer.DNSAudit(results)
+
+		if !fix {
+			return
+		}
+		if len(fixable) == 0 {
+			fmt.Println("no records to delete")
+			return
+		}
+		if !yes && !confirm(fmt.Sprintf("Delete %d unowned and dangling records?", len(fixable))) {
+			fmt.Println("no records deleted")
+			return
+		}
+
+		for _, r := range fixable {
+			if err := client.DomainRecord.Delete(ctx, r.Domain, r.ID); err != nil {
+				fmt.Printf("error while deleting dns record %s %s : %v\n", r.Type, r.Name, err)
+				os.Exit(1)
+			}
+			fmt.Printf("deleted %s %s %s\n", r.Type, r.Name, r.Data)
+		}
+	},
+}
+
+// addressSet is the addresses and networks the account owns
+type addressSet struct {
+	ips  map[string]bool
+	nets []*net.IPNet
+}
+
+func (s *addressSet) addIP(ip string) {
+	if parsed := net.ParseIP(ip); parsed != nil && !parsed.IsUnspecified() {
+		s.ips[parsed.String()] = true
+	}
+}
+
+func (s *addressSet) addNet(network string, size int) {
+	if network == "" || size == 0 {
+		return
+	}
+	if _, n, err := net.ParseCIDR(fmt.Sprintf("%s/%d", network, size)); err == nil {
+		s.nets = append(s.nets, n)
+	}
+}
+
+func (s *addressSet) contains(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	if s.ips[parsed.String()] {
+		return true
+	}
+	for _, n := range s.nets {
+		if n.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// ownedAddresses collects the addresses of every instance, bare metal
+// server, reserved IP and load balancer. IPv6 counts by network, as any
+// address in an instance's network can be in use.
+func ownedAddresses(ctx context.Context) (*addressSet, error) {
+	owned := &addressSet{ips: map[string]bool{}}
+
+	instances, err := listAllInstances(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, i := range instances {
+		owned.addIP(i.MainIP)
+		owned.addIP(i.V6MainIP)
+
+		v4, err := listAllIPv4(ctx, i.ID, client.Instance.ListIPv4)
+		if err != nil {
+			return nil, fmt.Errorf("instance %s : %v", i.ID, err)
+		}
+		for _, ip := range v4 {
+			owned.addIP(ip.IP)
+		}
+
+		v6, err := listAllIPv6(ctx, i.ID, client.Instance.ListIPv6)
+		if err != nil {
+			return nil, fmt.Errorf("instance %s : %v", i.ID, err)
+		}
+		for _, ip := range v6 {
+			owned.addIP(ip.IP)
+			owned.addNet(ip.Network, ip.NetworkSize)
+		}
+	}
+
+	servers, err := listAllBareMetal(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range servers {
+		owned.addIP(s.MainIP)
+		owned.addIP(s.V6MainIP)
+
+		v4, err := listAllIPv4(ctx, s.ID, client.BareMetalServer.ListIPv4s)
+		if err != nil {
+			return nil, fmt.Errorf("bare metal %s : %v", s.ID, err)
+		}
+		for _, ip := range v4 {
+			owned.addIP(ip.IP)
+		}
+
+		v6, err := listAllIPv6(ctx, s.ID, client.BareMetalServer.ListIPv6s)
+		if err != nil {
+			return nil, fmt.Errorf("bare metal %s : %v", s.ID, err)
+		}
+		for _, ip := range v6 {
+			owned.addIP(ip.IP)
+			owned.addNet(ip.Network, ip.NetworkSize)
+		}
+	}
+
+	options := &govultr.ListOptions{PerPage: 100}
+	for {
+		reserved, meta, err := client.ReservedIP.List(ctx, options)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range reserved {
+			owned.addIP(r.Subnet)
+			owned.addNet(r.Subnet, r.SubnetSize)
+		}
+
+		if meta == nil || meta.Links == nil || meta.Links.Next == "" {
+			break
+		}
+		options.Cursor = meta.Links.Next
+	}
+
+	options = &govultr.ListOptions{PerPage: 100}
+	for {
+		balancers, meta, err := client.LoadBalancer.List(ctx, options)
+		if err != nil {
+			return nil, err
+		}
+		for _, lb := range balancers {
+			owned.addIP(lb.IPV4)
+			owned.addIP(lb.IPV6)
+		}
+
+		if meta == nil || meta.Links == nil || meta.Links.Next == "" {
+			break
+		}
+		options.Cursor = meta.Links.Next
+	}
+
+	return owned, nil
+}
+
+// listAllIPv4 walks every page of the IPv4 addresses of an instance or bare
+// metal server, given the client method that lists them
+func listAllIPv4(ctx context.Context, id string, list func(context.Context, string, *govultr.ListOptions) ([]govultr.IPv4, *govultr.Meta, error)) ([]govultr.IPv4, error) {
+	var all []govultr.IPv4
+	options := &govultr.ListOptions{PerPage: 100}
+	for {
+		ips, meta, err := list(ctx, id, options)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, ips...)
+
+		if meta == nil || meta.Links == nil || meta.Links.Next == "" {
+			return all, nil
+		}
+		options.Cursor = meta.Links.Next
+	}
+}
+
+// listAllIPv6 walks every page of the IPv6 addresses of an instance or bare
+// metal server, given the client method that lists them
+func listAllIPv6(ctx context.Context, id string, list func(context.Context, string, *govultr.ListOptions) ([]govultr.IPv6, *govultr.Meta, error)) ([]govultr.IPv6, error) {
+	var all []govultr.IPv6
+	options := &govultr.ListOptions{PerPage: 100}
+	for {
+		ips, meta, err := list(ctx, id, options)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, ips...)
+
+		if meta == nil || meta.Links == nil || meta.Links.Next == "" {
+			return all, nil
+		}
+		options.Cursor = meta.Links.Next
+	}
+}
+
+// auditor checks records against the owned addresses. Names inside the
+// account's domains are resolved from their records, other names through
+// the system resolver.
+type auditor struct {
+	ctx    context.Context
+	owned  *addressSet
+	zones  []string
+	byName map[string][]govultr.DomainRecord
+}
+
+// check returns the status of a record and what it found out
+func (a *auditor) check(r govultr.DomainRecord) (string, string) {
+	if r.Type == "A" || r.Type == "AAAA" {
+		if a.owned.contains(r.Data) {
+			return auditOK, ""
+		}
+		return auditUnowned, ""
+	}
+
+	addresses, holder, err := a.resolve(canonicalName(r.Data), 0)
+	if err != nil {
+		var dnsErr *net.DNSError
+		if (errors.As(err, &dnsErr) && dnsErr.IsNotFound) || errors.Is(err, errNoRecords) {
+			return auditDangling, err.Error()
+		}
+		return auditLookupFailed, err.Error()
+	}
+
+	var foreign []string
+	for _, ip := range addresses {
+		if !a.owned.contains(ip) {
+			foreign = append(foreign, ip)
+		}
+	}
+	switch {
+	case len(foreign) == 0:
+		return auditOK, strings.Join(addresses, ", ")
+	case zoneForName(holder, a.zones) != "":
+		// The records holding the addresses are checked by themselves and
+		// fixing them fixes this one
+		return auditDependsOn, fmt.Sprintf("%s %s", holder, strings.Join(foreign, ", "))
+	default:
+		return auditExternal, strings.Join(foreign, ", ")
+	}
+}
+
+var errNoRecords = errors.New("no A, AAAA or CNAME records")
+
+// resolve follows a name to its addresses and returns them with the name
+// that holds them, the end of the CNAME chain
+func (a *auditor) resolve(name string, depth int) ([]string, string, error) {
+	if depth >= auditMaxChain {
+		return nil, "", fmt.Errorf("more than %d CNAMEs in a row", auditMaxChain)
+	}
+
+	if zoneForName(name, a.zones) == "" {
+		addresses, err := net.DefaultResolver.LookupHost(a.ctx, name+".")
+		return addresses, name, err
+	}
+
+	holder := name
+	records := a.byName[name]
+	if len(records) == 0 {
+		// A wildcard one level up answers for names without records
+		if dot := strings.Index(name, "."); dot >= 0 {
+			holder = "*" + name[dot:]
+			records = a.byName[holder]
+		}
+	}
+
+	var addresses []string
+	for _, r := range records {
+		switch r.Type {
+		case "A", "AAAA":
+			addresses = append(addresses, r.Data)
+		case "CNAME":
+			return a.resolve(canonicalName(r.Data), depth+1)
+		}
+	}
+
+	if len(addresses) == 0 {
+		return nil, "", fmt.Errorf("%s : %w", name, errNoRecords)
+	}
+	return addresses, holder, nil
+}
@@ -33,21 +33,40 @@ func DNSDomain() *cobra.Command {
 		Long:  ``,
 	}
 
-	dnsDomainCmd.AddCommand(domainCreate, domainGet, domainDelete, secEnable, secInfo, domainList, soaInfo, soaUpdate)
+	dnsDomainCmd.AddCommand(domainCreate, domainGet, domainDelete, secEnable, secInfo, secDSRecords, secCheck, domainList, soaInfo, soaUpdate, domainImport, domainExport, domainApplyTemplate)
 
 	// Create
-	domainCreate.Flags().StringP("domain", "d", "", "name of the domain")
-	domainCreate.MarkFlagRequired("domain")
+	domainCreate.Flags().StringP("domain", "d", "", "(optional) name of the domain, when it is not given as the argument")
 	domainCreate.Flags().StringP("ip", "i", "", "instance ip you want to assign this domain to")
+	domainCreate.Flags().StringP("template", "t", "", "(optional) zone template to create the records of")
+	domainCreate.Flags().StringArray("var", nil, "(optional) template variable as key=value. Can be given more than once")
+	domainCreate.Flags().Bool("keep-defaults", false, "(optional) with --template, keep the default records of the types and names the template has")
+
+	// Apply Template
+	domainApplyTemplate.Flags().StringP("template", "t", "", "zone template to create the records of")
+	domainApplyTemplate.MarkFlagRequired("template")
+	domainApplyTemplate.Flags().StringP("ip", "i", "", "(optional) IP address for {{.IP}} in the template")
+	domainApplyTemplate.Flags().StringArray("var", nil, "(optional) template variable as key=value. Can be given more than once")
+	domainApplyTemplate.Flags().Bool("dry-run", false, "(optional) show the records that would be created without creating them")
 
 	// Dns Sec
 	secEnable.Flags().StringP("enabled", "e", "", "set whether dns sec is enabled or not. true or false")
 	secEnable.MarkFlagRequired("enabled")
+	secDSRecords.Flags().StringP("format", "f", "table", "(optional) output format : table, zone or dnskey")
+	secCheck.Flags().Bool("fail-on-mismatch", false, "(optional) exit with status 1 when a key or DS record does not check out")
 
 	// Soa Update
 	soaUpdate.Flags().StringP("ns-primary", "n", "", "primary nameserver to store in the SOA record")
-	soaUpdate.MarkFlagRequired("ns-primary")
-	soaUpdate.Flags().StringP("email", "e", "", "administrative email to store in the SOA record")
+	soaUpdate.Flags().StringP("email", "e", "", "administrative email to store in the SOA record, as an address or RNAME")
+
+	// Import
+	domainImport.Flags().StringP("file", "f", "", "zone file to import")
+	domainImport.MarkFlagRequired("file")
+	domainImport.Flags().Bool("dry-run", false, "(optional) show the records that would be created without creating them")
+	domainImport.Flags().Bool("soa", false, "(optional) also set the primary nameserver and email of the SOA record")
+
+	// Export
+	domainExport.Flags().StringP("output", "o", "", "(optional) file to write to. Defaults to stdout")
 
 	// List
 	domainList.Flags().StringP("cursor", "c", "", "(optional) Cursor for paging.")
@@ -57,12 +76,58 @@ func DNSDomain() *cobra.Command {
 }
 
 var domainCreate = &cobra.Command{
-	Use:   "create",
+	Use:   "create <domainName>",
 	Short: "create a domain",
-	Long:  ``,
+	Long: `create adds a domain, given as the argument or with --domain. With --template
+the records of a zone template are created in it right after.
+
+With --ip Vultr gives a new domain default records, such as an A record for
+the apex and mail, a CNAME for www and an MX for mail. A default is deleted
+before the template is applied when the template has a record of the same
+type at the same name, so a template's apex MX replaces Vultr's instead of
+being added next to it. Defaults at other names or of other types, and the
+apex NS records, are kept. --keep-defaults keeps them all.
+
+` + zoneTemplateHelp,
+	Example: `
+	vultr-cli dns domain create example.com --ip 192.0.2.10 --template mail-google
+	`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		domain, _ := cmd.Flags().GetString("domain")
+		switch {
+		case len(args) > 1:
+			return errors.New("please provide only one domain name")
+		case len(args) == 0 && domain == "":
+			return errors.New("please provide a domain name")
+		case len(args) == 1 && domain != "" && canonicalName(domain) != canonicalName(args[0]):
+			return errors.New("please provide the domain name either as the argument or with --domain, not both")
+		}
+		return nil
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 		domain, _ := cmd.Flags().GetString("domain")
+		if len(args) > 0 {
+			domain = args[0]
+		}
 		ip, _ := cmd.Flags().GetString("ip")
+		templateName, _ := cmd.Flags().GetString("template")
+		keepDefaults, _ := cmd.Flags().GetBool("keep-defaults")
+
+		// Load the template first so a broken one does not leave a half
+		// set up domain behind
+		var records []govultr.DomainRecord
+		if templateName != "" {
+			vars, err := templateVars(cmd, canonicalName(domain), ip)
+			if err != nil {
+				fmt.Printf("error reading template variables : %v\n", err)
+				os.Exit(1)
+			}
+
+			if records, err = loadZoneTemplate(templateName, vars); err != nil {
+				fmt.Printf("error loading zone template : %v\n", err)
+				os.Exit(1)
+			}
+		}
 
 		options := &govultr.DomainReq{
 			Domain: domain,
@@ -76,6 +141,22 @@ var domainCreate = &cobra.Command{
 		}
 
 		printer.Domain(dns)
+
+		if templateName == "" {
+			return
+		}
+
+		changes, failed, err := applyZoneTemplate(context.Background(), dns.Domain, records, !keepDefaults, false)
+		if err != nil {
+			fmt.Printf("error while getting dns records : %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Println()
+		printer.DNSRecordChanges(changes)
+		if failed {
+			os.Exit(1)
+		}
 	},
 }
 
@@ -191,7 +272,7 @@ var soaInfo = &cobra.Command{
 		domain := args[0]
 		info, err := client.Domain.GetSoa(context.Background(), domain)
 		if err != nil {
-			fmt.Printf("error toggling dnssec : %v\n", err)
+			fmt.Printf("error getting soa info : %v\n", err)
 			os.Exit(1)
 		}
 
@@ -202,7 +283,12 @@ var soaInfo = &cobra.Command{
 var soaUpdate = &cobra.Command{
 	Use:   "soa-update <domainName>",
 	Short: "update soa for a domain",
-	Long:  ``,
+	Long: `soa-update sets the primary nameserver and the administrative email of a
+domain's SOA record. Fields that are not given keep their current value.
+
+The email can be given as an address, hostmaster@example.com, or as the RNAME
+it is published as, hostmaster.example.com. It is checked to convert to an
+RNAME and back without change.`,
 	Args: func(cmd *cobra.Command, args []string) error {
 		if len(args) < 1 {
 			return errors.New("please provide a domain name")
@@ -211,19 +297,40 @@ var soaUpdate = &cobra.Command{
 	},
 	Run: func(cmd *cobra.Command, args []string) {
 		domain := args[0]
-		nsPrimary, _ := cmd.Flags().GetString("ns-primary")
-		email, _ := cmd.Flags().GetString("email")
 
-		soaUpdate := &govultr.Soa{
-			NSPrimary: nsPrimary,
-			Email:     email,
+		if !cmd.Flags().Changed("ns-primary") && !cmd.Flags().Changed("email") {
+			fmt.Println("error updating soa : set --ns-primary, --email or both")
+			os.Exit(1)
 		}
 
-		if err := client.Domain.UpdateSoa(context.Background(), domain, soaUpdate); err != nil {
-			fmt.Printf("error toggling dnssec : %v\n", err)
+		soa, err := client.Domain.GetSoa(context.Background(), domain)
+		if err != nil {
+			fmt.Printf("error getting soa info : %v\n", err)
+			os.Exit(1)
+		}
+
+		if cmd.Flags().Changed("ns-primary") {
+			nsPrimary, _ := cmd.Flags().GetString("ns-primary")
+			if soa.NSPrimary, err = normalizeHostname(nsPrimary); err != nil {
+				fmt.Printf("error updating soa : primary nameserver %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		if cmd.Flags().Changed("email") {
+			email, _ := cmd.Flags().GetString("email")
+			if soa.Email, err = soaEmail(email); err != nil {
+				fmt.Printf("error updating soa : %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		if err := client.Domain.UpdateSoa(context.Background(), domain, soa); err != nil {
+			fmt.Printf("error updating soa : %v\n", err)
 			os.Exit(1)
 		}
 
 		fmt.Println("updated SOA")
+		printer.SoaInfo(soa)
 	},
 }
@@ -19,7 +19,6 @@ import (
 	"errors"
 	"fmt"
 	"os"
-	"regexp"
 
 	"github.com/spf13/cobra"
 	"github.com/vultr/govultr/v2"
@@ -34,7 +33,7 @@ func DNSRecord() *cobra.Command {
 		Long:  ``,
 	}
 
-	dnsRecordCmd.AddCommand(recordCreate, recordGet, recordList, recordDelete, recordUpdate)
+	dnsRecordCmd.AddCommand(recordCreate, recordGet, recordList, recordDelete, recordUpdate, recordSync)
 
 	// Create
 	recordCreate.Flags().StringP("domain", "m", "", "name of domain you want to create this record for")
@@ -55,18 +54,23 @@ func DNSRecord() *cobra.Command {
 	recordUpdate.Flags().IntP("ttl", "", 0, "time to live for the record")
 	recordUpdate.Flags().IntP("priority", "p", -1, "only required for MX and SRV")
 
+	// Sync
+	recordSync.Flags().StringP("file", "f", "", "YAML file with the records the domain should have")
+	recordSync.MarkFlagRequired("file")
+	recordSync.Flags().String("owner", "default", "(optional) owner stored in the marker records, to keep several sync files apart")
+	recordSync.Flags().Bool("prune", false, "(optional) delete owned records that are not in the file")
+	recordSync.Flags().Bool("dry-run", false, "(optional) only show the differences")
+
 	// List
 	recordList.Flags().StringP("cursor", "c", "", "(optional) Cursor for paging.")
 	recordList.Flags().IntP("per-page", "p", 100, "(optional) Number of items requested per page. Default is 100 and Max is 500.")
+	recordList.Flags().StringP("type", "t", "", "(optional) only list records of this type")
+	recordList.Flags().StringP("name", "n", "", "(optional) only list records with this name, @ for the domain itself; * and ? match any characters")
+	recordList.Flags().StringP("data", "d", "", "(optional) only list records with this data; * and ? match any characters")
 
 	return dnsRecordCmd
 }
 
-// Temporary solution to determine if the record type is TXT, in order to
-// add quotes around the value. The API does not accept TXT records without
-//	quotes.
-var regRecordTxt = regexp.MustCompile("([A-Z]|=|_)")
-
 var recordCreate = &cobra.Command{
 	Use:   "create",
 	Short: "create a dns record",
@@ -76,13 +80,15 @@ var recordCreate = &cobra.Command{
 		rType, _ := cmd.Flags().GetString("type")
 		name, _ := cmd.Flags().GetString("name")
 		data, _ := cmd.Flags().GetString("data")
-		// Record data for TXT must be enclosed in quotes
-		if data[0] != '"' && data[len(data)-1] != '"' && regRecordTxt.Match([]byte(data)) {
-			data = fmt.Sprintf("\"%s\"", data)
-		}
 		ttl, _ := cmd.Flags().GetInt("ttl")
 		priority, _ := cmd.Flags().GetInt("priority")
 
+		data, err := normalizeRecordData(rType, data)
+		if err != nil {
+			fmt.Printf("error while creating dns record : %v\n", err)
+			os.Exit(1)
+		}
+
 		options := &govultr.DomainRecordReq{
 			Name:     name,
 			Type:     rType,
@@ -137,8 +143,22 @@ var recordList = &cobra.Command{
 	},
 	Run: func(cmd *cobra.Command, args []string) {
 		domain := args[0]
-		options := getPaging(cmd)
+		filter := newRecordFilter(cmd)
+
+		// Filtering is done here, so every page is needed to filter on
+		if filter.active() {
+			records, err := listAllDomainRecords(context.Background(), domain)
+			if err != nil {
+				fmt.Printf("error while getting dns records : %v\n", err)
+				os.Exit(1)
+			}
+
+			records = filter.apply(records)
+			printer.DnsRecordsList(records, &govultr.Meta{Total: len(records), Links: &govultr.Links{}})
+			return
+		}
 
+		options := getPaging(cmd)
 		records, meta, err := client.DomainRecord.List(context.Background(), domain, options)
 		if err != nil {
 			fmt.Printf("error while getting dns records : %v\n", err)
@@ -197,12 +217,18 @@ var recordUpdate = &cobra.Command{
 			updates.Name = name
 		}
 
-		if data != "" {
-			// Record data for TXT must be enclosed in quotes
-			if data[0] != '"' && data[len(data)-1] != '"' && regRecordTxt.Match([]byte(data)) {
-				data = fmt.Sprintf("\"%s\"", data)
+		if cmd.Flags().Changed("data") {
+			// The data is checked against the type of the record
+			record, err := client.DomainRecord.Get(context.Background(), domain, id)
+			if err != nil {
+				fmt.Printf("error while getting dns records : %v\n", err)
+				os.Exit(1)
+			}
+
+			if updates.Data, err = normalizeRecordData(record.Type, data); err != nil {
+				fmt.Printf("error updating dns record : %v\n", err)
+				os.Exit(1)
 			}
-			updates.Data = data
 		}
 
 		if ttl != 0 {
@@ -0,0 +1,245 @@
+// Copyright © 2019 The Vultr-cli Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// txtChunkSize is the longest character-string a TXT record can hold
+const txtChunkSize = 255
+
+// normalizeRecordData checks record data against its type and returns it in
+// the form the API stores, so bad input gets a clear error here instead of a
+// rejection from the API.
+func normalizeRecordData(rType, data string) (string, error) {
+	data = strings.TrimSpace(data)
+	if data == "" {
+		return "", errors.New("record data can not be empty")
+	}
+
+	switch strings.ToUpper(rType) {
+	case "A":
+		ip := net.ParseIP(data)
+		if ip == nil || ip.To4() == nil || strings.Contains(data, ":") {
+			return "", fmt.Errorf("%q is not an IPv4 address", data)
+		}
+		return ip.String(), nil
+	case "AAAA":
+		ip := net.ParseIP(data)
+		if ip == nil || !strings.Contains(data, ":") {
+			return "", fmt.Errorf("%q is not an IPv6 address", data)
+		}
+		return ip.String(), nil
+	case "CNAME", "NS", "MX":
+		// A null MX, RFC 7505, says the domain takes no mail
+		if strings.ToUpper(rType) == "MX" && strings.TrimSpace(data) == "." {
+			return ".", nil
+		}
+		if fields := strings.Fields(data); len(fields) > 1 {
+			return "", fmt.Errorf("%s data is a single host name, got %q. Use --priority for the MX priority", strings.ToUpper(rType), data)
+		}
+		return normalizeHostname(data)
+	case "SRV":
+		return normalizeSRV(data)
+	case "CAA":
+		return normalizeCAA(data)
+	case "SSHFP":
+		return normalizeSSHFP(data)
+	case "TXT":
+		return formatTXT(splitTXT(data)), nil
+	default:
+		return data, nil
+	}
+}
+
+// normalizeHostname checks a host name and returns it without the trailing
+// dot, as the API stores fully qualified names
+func normalizeHostname(name string) (string, error) {
+	name = strings.TrimSuffix(name, ".")
+	if name == "" || len(name) > 253 {
+		return "", fmt.Errorf("%q is not a valid host name", name)
+	}
+
+	for _, label := range strings.Split(name, ".") {
+		if len(label) == 0 || len(label) > 63 {
+			return "", fmt.Errorf("%q is not a valid host name", name)
+		}
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+				return "", fmt.Errorf("%q is not a valid host name", name)
+			}
+		}
+	}
+
+	return strings.ToLower(name), nil
+}
+
+// normalizeSRV checks SRV data, which is weight, port and target with the
+// priority in its own field
+func normalizeSRV(data string) (string, error) {
+	fields := strings.Fields(data)
+	if len(fields) == 4 {
+		return "", fmt.Errorf("SRV data is weight port target, got %q. Use --priority for the priority", data)
+	}
+	if len(fields) != 3 {
+		return "", fmt.Errorf("SRV data is weight port target, got %q", data)
+	}
+
+	for i, what := range []string{"weight", "port"} {
+		n, err := strconv.Atoi(fields[i])
+		if err != nil || n < 0 || n > 65535 {
+			return "", fmt.Errorf("SRV %s must be a number between 0 and 65535, got %q", what, fields[i])
+		}
+	}
+
+	// A target of . means the service is not available
+	target := fields[2]
+	if target != "." {
+		var err error
+		if target, err = normalizeHostname(target); err != nil {
+			return "", err
+		}
+	}
+
+	return strings.Join([]string{fields[0], fields[1], target}, " "), nil
+}
+
+// normalizeCAA checks CAA data, which is flags, tag and a quoted value
+func normalizeCAA(data string) (string, error) {
+	fields := strings.Fields(data)
+	if len(fields) < 3 {
+		return "", fmt.Errorf(`CAA data is flags tag value, such as 0 issue "letsencrypt.org", got %q`, data)
+	}
+
+	// The value is everything after the tag, spaces included
+	rest := strings.TrimSpace(data)
+	for _, f := range fields[:2] {
+		rest = strings.TrimSpace(strings.TrimPrefix(rest, f))
+	}
+	fields = []string{fields[0], fields[1], rest}
+
+	flags, err := strconv.Atoi(fields[0])
+	if err != nil || flags < 0 || flags > 255 {
+		return "", fmt.Errorf("CAA flags must be a number between 0 and 255, got %q", fields[0])
+	}
+
+	tag := strings.ToLower(fields[1])
+	for _, c := range tag {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9') {
+			return "", fmt.Errorf("CAA tag must be alphanumeric, such as issue, issuewild or iodef, got %q", fields[1])
+		}
+	}
+
+	value := strings.TrimSpace(fields[2])
+	if len(value) >= 2 && strings.HasPrefix(value, `"`) && strings.HasSuffix(value, `"`) {
+		value = value[1 : len(value)-1]
+	}
+	if strings.Contains(value, `"`) {
+		return "", fmt.Errorf("CAA value can not contain quotes, got %q", fields[2])
+	}
+
+	return fmt.Sprintf(`%d %s "%s"`, flags, tag, value), nil
+}
+
+// normalizeSSHFP checks SSHFP data, which is algorithm, fingerprint type and
+// the fingerprint in hex
+func normalizeSSHFP(data string) (string, error) {
+	fields := strings.Fields(data)
+	if len(fields) < 3 {
+		return "", fmt.Errorf("SSHFP data is algorithm type fingerprint, got %q", data)
+	}
+
+	algorithm, err := strconv.Atoi(fields[0])
+	if err != nil || algorithm < 1 || algorithm > 6 || algorithm == 5 {
+		return "", fmt.Errorf("SSHFP algorithm must be 1 (RSA), 2 (DSA), 3 (ECDSA), 4 (Ed25519) or 6 (Ed448), got %q", fields[0])
+	}
+
+	fpType, err := strconv.Atoi(fields[1])
+	if err != nil || fpType < 1 || fpType > 2 {
+		return "", fmt.Errorf("SSHFP fingerprint type must be 1 (SHA-1) or 2 (SHA-256), got %q", fields[1])
+	}
+
+	fingerprint := strings.ToLower(strings.Join(fields[2:], ""))
+	raw, err := hex.DecodeString(fingerprint)
+	if err != nil {
+		return "", fmt.Errorf("SSHFP fingerprint must be hex, got %q", fingerprint)
+	}
+	if want := map[int]int{1: 20, 2: 32}[fpType]; len(raw) != want {
+		return "", fmt.Errorf("SSHFP fingerprint of type %d must be %d hex characters, got %d", fpType, want*2, len(fingerprint))
+	}
+
+	return fmt.Sprintf("%d %d %s", algorithm, fpType, fingerprint), nil
+}
+
+// splitTXT returns the character-strings of TXT data with their escapes as
+// written. Data that is not quoted is one string, escaped as needed.
+func splitTXT(data string) []string {
+	if !strings.HasPrefix(data, `"`) {
+		return []string{strings.ReplaceAll(strings.ReplaceAll(data, `\`, `\\`), `"`, `\"`)}
+	}
+
+	lines, err := tokenizeZone(strings.ReplaceAll(data, "\n", " "))
+	if err != nil || len(lines) != 1 {
+		// Not well formed, keep what is between the outer quotes
+		return []string{strings.TrimSuffix(strings.TrimPrefix(data, `"`), `"`)}
+	}
+
+	parts := make([]string, 0, len(lines[0].tokens))
+	for _, t := range lines[0].tokens {
+		parts = append(parts, t.text)
+	}
+	return parts
+}
+
+// formatTXT joins character-strings and splits them again into quoted
+// strings of at most 255 characters, without breaking an escape sequence.
+// This is the one form TXT data is written in, so records compare equal
+// however they were entered.
+func formatTXT(parts []string) string {
+	inner := strings.Join(parts, "")
+
+	var chunks []string
+	for len(inner) > txtChunkSize {
+		cut := 0
+		for cut < len(inner) {
+			next := cut + 1
+			if inner[cut] == '\\' && cut+1 < len(inner) {
+				next = cut + 2
+				if cut+3 < len(inner) && isDigit(inner[cut+1]) && isDigit(inner[cut+2]) && isDigit(inner[cut+3]) {
+					next = cut + 4
+				}
+			}
+			if next > txtChunkSize {
+				break
+			}
+			cut = next
+		}
+		chunks = append(chunks, inner[:cut])
+		inner = inner[cut:]
+	}
+	chunks = append(chunks, inner)
+
+	return `"` + strings.Join(chunks, `" "`) + `"`
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,96 @@
+// Copyright © 2019 The Vultr-cli Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizeRecordData(t *testing.T) {
+	sha256FP := strings.Repeat("ab", 32)
+	long := strings.Repeat("a", 300)
+
+	tests := []struct {
+		rType string
+		data  string
+		want  string
+		err   string
+	}{
+		{"A", " 192.0.2.1 ", "192.0.2.1", ""},
+		{"A", "2001:db8::1", "", "not an IPv4 address"},
+		{"A", "::ffff:192.0.2.1", "", "not an IPv4 address"},
+		{"A", "192.0.2", "", "not an IPv4 address"},
+		{"AAAA", "2001:DB8:0::1", "2001:db8::1", ""},
+		{"AAAA", "192.0.2.1", "", "not an IPv6 address"},
+		{"a", "192.0.2.1", "192.0.2.1", ""},
+
+		{"CNAME", "WWW.Example.com.", "www.example.com", ""},
+		{"CNAME", "_dmarc.example.com", "_dmarc.example.com", ""},
+		{"CNAME", "bad..example.com", "", "not a valid host name"},
+		{"CNAME", "bad host", "", "single host name"},
+		{"CNAME", ".", "", "not a valid host name"},
+		{"NS", "ns1.vultr.com.", "ns1.vultr.com", ""},
+		{"MX", "mx1.example.com", "mx1.example.com", ""},
+		{"MX", "10 mx1.example.com", "", "Use --priority"},
+		{"MX", ".", ".", ""},
+		{"MX", " . ", ".", ""},
+
+		{"SRV", "20 5060 sip.example.com.", "20 5060 sip.example.com", ""},
+		{"SRV", "0 0 .", "0 0 .", ""},
+		{"SRV", "10 20 5060 sip.example.com", "", "Use --priority"},
+		{"SRV", "20 70000 sip.example.com", "", "SRV port"},
+		{"SRV", "20 5060", "", "SRV data is weight port target"},
+
+		{"CAA", `0 issue "letsencrypt.org"`, `0 issue "letsencrypt.org"`, ""},
+		{"CAA", `0  ISSUE   letsencrypt.org`, `0 issue "letsencrypt.org"`, ""},
+		{"CAA", "128\tiodef\t\"mailto:security@example.com\"", `128 iodef "mailto:security@example.com"`, ""},
+		{"CAA", `0 issue ";"`, `0 issue ";"`, ""},
+		{"CAA", `0 issue "a b"`, `0 issue "a b"`, ""},
+		{"CAA", `0 issue`, "", "CAA data is flags tag value"},
+		{"CAA", `256 issue "ca.example"`, "", "CAA flags"},
+		{"CAA", `0 is-sue "ca.example"`, "", "CAA tag"},
+		{"CAA", `0 issue "ca"example"`, "", "can not contain quotes"},
+
+		{"SSHFP", "4 2 " + strings.ToUpper(sha256FP), "4 2 " + sha256FP, ""},
+		{"SSHFP", "4 2 " + sha256FP[:32] + " " + sha256FP[32:], "4 2 " + sha256FP, ""},
+		{"SSHFP", "5 2 " + sha256FP, "", "SSHFP algorithm"},
+		{"SSHFP", "4 3 " + sha256FP, "", "SSHFP fingerprint type"},
+		{"SSHFP", "4 1 " + sha256FP, "", "must be 40 hex characters"},
+		{"SSHFP", "4 2 zz", "", "must be hex"},
+
+		{"TXT", "v=spf1 -all", `"v=spf1 -all"`, ""},
+		{"TXT", `"v=spf1 -all"`, `"v=spf1 -all"`, ""},
+		{"TXT", `"v=DKIM1; " "p=abc"`, `"v=DKIM1; p=abc"`, ""},
+		{"TXT", `say "hi"`, `"say \"hi\""`, ""},
+		{"TXT", long, `"` + long[:255] + `" "` + long[255:] + `"`, ""},
+
+		{"TXT", "  ", "", "can not be empty"},
+		{"NAPTR", "anything goes", "anything goes", ""},
+	}
+
+	for _, tt := range tests {
+		got, err := normalizeRecordData(tt.rType, tt.data)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("normalizeRecordData(%s, %q) error = %v, want %q", tt.rType, tt.data, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("normalizeRecordData(%s, %q) = %q, %v, want %q", tt.rType, tt.data, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,481 @@
+// Copyright © 2019 The Vultr-cli Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/vultr/govultr/v2"
+	"github.com/vultr/vultr-cli/v2/cmd/printer"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// ownerMarkerPrefix names the TXT records that mark which names sync
+	// manages, in the style of external-dns
+	ownerMarkerPrefix = "_vultr-cli-owner"
+	ownerMarkerData   = "heritage=vultr-cli,owner="
+	ownerMarkerRecord = "record="
+)
+
+var recordSync = &cobra.Command{
+	Use:   "sync <domainName>",
+	Short: "make the records of a domain match a YAML file",
+	Long: `sync compares the records in a YAML file with the live records of a domain
+and makes the fewest changes to match them. Records are matched on name, type
+and data; a changed TTL or priority is updated in place, as is the data of a
+record when there is one left of the same name and type.
+
+Every record sync creates is claimed by a TXT record named
+_vultr-cli-owner.<name> holding the --owner and a hash of the record. Only
+claimed records are updated or, with --prune, deleted, so records created by
+other tools or by hand are left alone, even at the same name. A record in the
+file that already exists unclaimed is left as it is.
+
+The file lists the records under a records key. Names are relative to the
+domain, with "" or @ for the apex; a name ending in a dot is fully qualified
+and has to be in the domain.
+
+  records:
+    - name: www
+      type: A
+      data: 192.0.2.10
+      ttl: 300
+    - name: ""
+      type: MX
+      data: mail.example.com
+      priority: 10`,
+	Example: `
+	# Preview the changes
+	vultr-cli dns record sync example.com -f records.yaml --dry-run
+
+	# Apply them and delete the managed records that are no longer in the file
+	vultr-cli dns record sync example.com -f records.yaml --prune
+	`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 {
+			return errors.New("please provide a domain name")
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		domain := args[0]
+		file, _ := cmd.Flags().GetString("file")
+		owner, _ := cmd.Flags().GetString("owner")
+		prune, _ := cmd.Flags().GetBool("prune")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		desired, err := loadRecordSet(file, domain)
+		if err != nil {
+			fmt.Printf("error reading %s : %v\n", file, err)
+			os.Exit(1)
+		}
+
+		ctx := context.Background()
+		live, err := listAllDomainRecords(ctx, domain)
+		if err != nil {
+			fmt.Printf("error while getting dns records : %v\n", err)
+			os.Exit(1)
+		}
+
+		plan, err := planRecordSync(owner, desired, live, prune)
+		if err != nil {
+			fmt.Printf("error syncing dns records : %v\n", err)
+			os.Exit(1)
+		}
+
+		diff := unifiedDiff("live", file, renderZone(domain, nil, plan.Before, ""), renderZone(domain, nil, plan.After, ""))
+		if diff == "" {
+			fmt.Println("Records are in sync")
+			return
+		}
+		fmt.Print(diff)
+
+		if dryRun {
+			return
+		}
+
+		if failed := applyRecordSync(ctx, domain, plan); failed {
+			os.Exit(1)
+		}
+	},
+}
+
+// recordSpec is one record of a sync file
+type recordSpec struct {
+	Name     string `yaml:"name"`
+	Type     string `yaml:"type"`
+	Data     string `yaml:"data"`
+	TTL      int    `yaml:"ttl"`
+	Priority int    `yaml:"priority"`
+}
+
+type recordSet struct {
+	Records []recordSpec `yaml:"records"`
+}
+
+// loadRecordSet reads a sync file into API records
+func loadRecordSet(file, domain string) ([]govultr.DomainRecord, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return parseRecordSet(data, domain)
+}
+
+// parseRecordSet reads the records of a sync file or zone template,
+// normalizing their data. Names are relative to domain; fully qualified
+// names in the domain are made relative and others are rejected.
+func parseRecordSet(data []byte, domain string) ([]govultr.DomainRecord, error) {
+	var set recordSet
+	if err := yaml.UnmarshalStrict(data, &set); err != nil {
+		return nil, err
+	}
+	return set.records(domain)
+}
+
+// records turns the records of a sync file into API records
+func (set recordSet) records(domain string) ([]govultr.DomainRecord, error) {
+	records := make([]govultr.DomainRecord, 0, len(set.Records))
+	for i, s := range set.Records {
+		name, err := recordSetName(s.Name, domain)
+		if err != nil {
+			return nil, fmt.Errorf("record %d : %v", i+1, err)
+		}
+
+		r := govultr.DomainRecord{
+			Name:     name,
+			Type:     strings.ToUpper(s.Type),
+			Data:     strings.TrimSpace(s.Data),
+			TTL:      s.TTL,
+			Priority: s.Priority,
+		}
+		if r.Type == "" {
+			return nil, fmt.Errorf("record %d needs a type", i+1)
+		}
+
+		if r.Data, err = normalizeRecordData(r.Type, r.Data); err != nil {
+			return nil, fmt.Errorf("record %d : %v", i+1, err)
+		}
+		records = append(records, r)
+	}
+
+	return records, nil
+}
+
+// recordSetName makes a record name of a sync file relative to the domain.
+// A name ending in a dot is fully qualified and has to be in the domain, as
+// has a name without the dot that ends in the domain.
+func recordSetName(name, domain string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "@" {
+		return "", nil
+	}
+
+	if relative, ok := relativeName(name, domain); ok {
+		return relative, nil
+	}
+	if strings.HasSuffix(name, ".") {
+		return "", fmt.Errorf("%s is not in %s", name, domain)
+	}
+	return canonicalName(name), nil
+}
+
+// recordSyncUpdate is a live record and what it becomes
+type recordSyncUpdate struct {
+	From govultr.DomainRecord
+	To   govultr.DomainRecord
+}
+
+// recordSyncPlan is the set of changes that makes a domain match a file.
+// Before and After are the records sync looks at, for the diff.
+type recordSyncPlan struct {
+	Create []govultr.DomainRecord
+	Update []recordSyncUpdate
+	Delete []govultr.DomainRecord
+	Before []govultr.DomainRecord
+	After  []govultr.DomainRecord
+}
+
+// ownerMarkerName is the name of the marker records for a record name
+func ownerMarkerName(name string) string {
+	if name == "" {
+		return ownerMarkerPrefix
+	}
+	// A * is only allowed as the first label, so it can not follow the prefix
+	return ownerMarkerPrefix + "." + strings.ReplaceAll(name, "*", "_wildcard")
+}
+
+func isOwnerMarker(r govultr.DomainRecord) bool {
+	return r.Type == "TXT" && (r.Name == ownerMarkerPrefix || strings.HasPrefix(r.Name, ownerMarkerPrefix+"."))
+}
+
+// ownershipKey identifies the one record a marker claims. It is a hash so
+// that long TXT data fits in the marker.
+func ownershipKey(r govultr.DomainRecord) string {
+	sum := sha256.Sum256([]byte(recordMatchKey(r)))
+	return r.Type + ":" + hex.EncodeToString(sum[:8])
+}
+
+// ownerMarker is the marker record that claims r for owner
+func ownerMarker(owner string, r govultr.DomainRecord) govultr.DomainRecord {
+	return govultr.DomainRecord{
+		Name: ownerMarkerName(r.Name),
+		Type: "TXT",
+		Data: fmt.Sprintf(`"%s%s,%s%s"`, ownerMarkerData, owner, ownerMarkerRecord, ownershipKey(r)),
+	}
+}
+
+// parseOwnerMarker returns the owner and the ownership key held by a marker
+// record. Markers without a record key claim nothing.
+func parseOwnerMarker(r govultr.DomainRecord) (string, string) {
+	data := strings.Trim(r.Data, `"`)
+	if !strings.HasPrefix(data, ownerMarkerData) {
+		return "", ""
+	}
+	data = strings.TrimPrefix(data, ownerMarkerData)
+
+	comma := strings.Index(data, ","+ownerMarkerRecord)
+	if comma < 0 {
+		return data, ""
+	}
+	return data[:comma], data[comma+1+len(ownerMarkerRecord):]
+}
+
+// recordMatchKey identifies a record by name, type and data
+func recordMatchKey(r govultr.DomainRecord) string {
+	return fmt.Sprintf("%s|%s|%s", r.Type, canonicalName(r.Name), comparableData(r))
+}
+
+// planRecordSync works out the changes that turn live into desired for the
+// records owned by owner. Each record sync creates is claimed by a marker of
+// its own, and only claimed records are updated or deleted. A record in the
+// file that already exists without a marker is left as it is. It fails when
+// the file holds a record that another owner claims.
+func planRecordSync(owner string, desired, live []govultr.DomainRecord, prune bool) (*recordSyncPlan, error) {
+	plan := &recordSyncPlan{}
+
+	// Who claims which record, going by the marker records
+	claims := map[string]string{}
+	markers := map[string]govultr.DomainRecord{}
+	for _, r := range live {
+		if !isOwnerMarker(r) {
+			continue
+		}
+		if o, key := parseOwnerMarker(r); key != "" {
+			claims[key] = o
+			markers[key] = r
+		}
+	}
+	ownerOf := func(r govultr.DomainRecord) string {
+		return claims[ownershipKey(r)]
+	}
+
+	desiredNames := map[string]bool{}
+	for _, d := range desired {
+		desiredNames[d.Name] = true
+	}
+
+	// Records sync looks at: the owned ones and the others at the names in
+	// the file, which are shown in the diff but never changed
+	liveByKey := map[string][]int{}
+	for i, r := range live {
+		if isOwnerMarker(r) || !(ownerOf(r) == owner || desiredNames[canonicalName(r.Name)]) {
+			continue
+		}
+		plan.Before = append(plan.Before, r)
+		liveByKey[recordMatchKey(r)] = append(liveByKey[recordMatchKey(r)], i)
+	}
+
+	used := make([]bool, len(live))
+	var unmatched []govultr.DomainRecord
+	for _, d := range desired {
+		if o, ok := claims[ownershipKey(d)]; ok && o != owner {
+			return nil, fmt.Errorf("%s %s %s is managed by owner %q", displayName(d.Name), d.Type, d.Data, o)
+		}
+
+		matches := liveByKey[recordMatchKey(d)]
+		if len(matches) == 0 {
+			unmatched = append(unmatched, d)
+			continue
+		}
+
+		i := matches[0]
+		liveByKey[recordMatchKey(d)] = matches[1:]
+		used[i] = true
+
+		if ownerOf(live[i]) == owner && recordNeedsUpdate(live[i], d) {
+			plan.Update = append(plan.Update, recordSyncUpdate{From: live[i], To: mergeRecord(live[i], d)})
+		}
+	}
+
+	// Reuse a left over owned record of the same name and type rather than
+	// deleting one and creating another, moving its claim along
+	for _, d := range unmatched {
+		reused := false
+		for i, r := range live {
+			if used[i] || isOwnerMarker(r) || ownerOf(r) != owner || r.Type != d.Type || canonicalName(r.Name) != d.Name {
+				continue
+			}
+			used[i] = true
+			to := mergeRecord(r, d)
+			plan.Update = append(plan.Update, recordSyncUpdate{From: r, To: to})
+
+			marker := ownerMarker(owner, to)
+			marker.ID = markers[ownershipKey(r)].ID
+			plan.Update = append(plan.Update, recordSyncUpdate{From: markers[ownershipKey(r)], To: marker})
+			delete(markers, ownershipKey(r))
+			reused = true
+			break
+		}
+		if !reused {
+			plan.Create = append(plan.Create, d, ownerMarker(owner, d))
+		}
+	}
+
+	// Owned records that are no longer in the file
+	for i, r := range live {
+		if isOwnerMarker(r) || ownerOf(r) != owner {
+			continue
+		}
+		key := ownershipKey(r)
+		if !used[i] && prune {
+			plan.Delete = append(plan.Delete, r)
+			if marker, ok := markers[key]; ok {
+				plan.Delete = append(plan.Delete, marker)
+			}
+		}
+		delete(markers, key)
+	}
+
+	// Markers of this owner whose record is gone
+	if prune {
+		for _, key := range sortedMarkerKeys(markers) {
+			if claims[key] == owner {
+				plan.Delete = append(plan.Delete, markers[key])
+			}
+		}
+	}
+
+	plan.After = recordsAfter(plan)
+	return plan, nil
+}
+
+func sortedMarkerKeys(m map[string]govultr.DomainRecord) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// recordNeedsUpdate reports whether a matched record differs in TTL or
+// priority. A TTL of 0 in the file means any TTL will do.
+func recordNeedsUpdate(live, desired govultr.DomainRecord) bool {
+	if desired.TTL != 0 && desired.TTL != live.TTL {
+		return true
+	}
+	return (desired.Type == "MX" || desired.Type == "SRV") && desired.Priority != live.Priority
+}
+
+// mergeRecord is the live record with the desired fields laid over it
+func mergeRecord(live, desired govultr.DomainRecord) govultr.DomainRecord {
+	merged := desired
+	merged.ID = live.ID
+	if merged.TTL == 0 {
+		merged.TTL = live.TTL
+	}
+	return merged
+}
+
+// recordsAfter applies the plan to the records it looked at, leaving out
+// the marker records as the diff is about the records in the file
+func recordsAfter(plan *recordSyncPlan) []govultr.DomainRecord {
+	gone := map[string]bool{}
+	for _, r := range plan.Delete {
+		gone[r.ID] = true
+	}
+	replaced := map[string]govultr.DomainRecord{}
+	for _, u := range plan.Update {
+		replaced[u.From.ID] = u.To
+	}
+
+	var after []govultr.DomainRecord
+	for _, r := range plan.Before {
+		if gone[r.ID] {
+			continue
+		}
+		if to, ok := replaced[r.ID]; ok {
+			r = to
+		}
+		after = append(after, r)
+	}
+	for _, r := range plan.Create {
+		if !isOwnerMarker(r) {
+			after = append(after, r)
+		}
+	}
+	return after
+}
+
+// applyRecordSync makes the changes of a plan, creating before deleting so a
+// name is never left without records. It reports whether any change failed.
+func applyRecordSync(ctx context.Context, domain string, plan *recordSyncPlan) bool {
+	var changes []printer.DNSRecordChange
+	failed := false
+
+	result := func(action string, r govultr.DomainRecord, err error) {
+		if err != nil {
+			action = fmt.Sprintf("error : %v", err)
+			failed = true
+		}
+		changes = append(changes, recordChange(action, r))
+	}
+
+	for _, r := range plan.Create {
+		_, err := client.DomainRecord.Create(ctx, domain, recordReq(r))
+		result("created", r, err)
+	}
+
+	for _, u := range plan.Update {
+		req := recordReq(u.To)
+		result("updated", u.To, client.DomainRecord.Update(ctx, domain, u.From.ID, req))
+	}
+
+	for _, r := range plan.Delete {
+		result("deleted", r, client.DomainRecord.Delete(ctx, domain, r.ID))
+	}
+
+	printer.DNSRecordChanges(changes)
+	return failed
+}
+
+// displayName shows the apex as @
+func displayName(name string) string {
+	if name == "" {
+		return "@"
+	}
+	return name
+}
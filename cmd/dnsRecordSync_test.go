@@ -0,0 +1,196 @@
+// Copyright © 2019 The Vultr-cli Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/vultr/govultr/v2"
+)
+
+func TestPlanRecordSync(t *testing.T) {
+	marker := func(owner string, r govultr.DomainRecord, id string) govultr.DomainRecord {
+		m := ownerMarker(owner, r)
+		m.ID = id
+		return m
+	}
+
+	owned := govultr.DomainRecord{ID: "1", Type: "A", Name: "www", Data: "192.0.2.1", TTL: 300}
+	ownedMarker := marker("me", owned, "2")
+	hand := govultr.DomainRecord{ID: "3", Type: "A", Name: "www", Data: "192.0.2.9", TTL: 300}
+	elsewhere := govultr.DomainRecord{ID: "4", Type: "A", Name: "mail", Data: "192.0.2.20", TTL: 300}
+	theirs := govultr.DomainRecord{ID: "5", Type: "TXT", Name: "", Data: `"v=spf1 -all"`, TTL: 300}
+	theirMarker := marker("them", theirs, "6")
+
+	newWWW := govultr.DomainRecord{Type: "A", Name: "www", Data: "192.0.2.5", TTL: 300}
+
+	tests := []struct {
+		name    string
+		desired []govultr.DomainRecord
+		live    []govultr.DomainRecord
+		prune   bool
+		create  []govultr.DomainRecord
+		update  []recordSyncUpdate
+		delete  []govultr.DomainRecord
+		before  []govultr.DomainRecord
+		err     string
+	}{
+		{
+			name:    "new record gets a marker",
+			desired: []govultr.DomainRecord{newWWW},
+			create:  []govultr.DomainRecord{newWWW, ownerMarker("me", newWWW)},
+		},
+		{
+			name:    "owned record in the file is kept",
+			desired: []govultr.DomainRecord{{Type: "A", Name: "www", Data: "192.0.2.1"}},
+			live:    []govultr.DomainRecord{owned, ownedMarker},
+			before:  []govultr.DomainRecord{owned},
+		},
+		{
+			name:    "owned record with a new TTL is updated in place",
+			desired: []govultr.DomainRecord{{Type: "A", Name: "www", Data: "192.0.2.1", TTL: 60}},
+			live:    []govultr.DomainRecord{owned, ownedMarker},
+			update: []recordSyncUpdate{
+				{From: owned, To: govultr.DomainRecord{ID: "1", Type: "A", Name: "www", Data: "192.0.2.1", TTL: 60}},
+			},
+			before: []govultr.DomainRecord{owned},
+		},
+		{
+			name:    "owned record with new data is reused and its claim moved",
+			desired: []govultr.DomainRecord{newWWW},
+			live:    []govultr.DomainRecord{owned, ownedMarker},
+			update: []recordSyncUpdate{
+				{From: owned, To: govultr.DomainRecord{ID: "1", Type: "A", Name: "www", Data: "192.0.2.5", TTL: 300}},
+				{From: ownedMarker, To: marker("me", newWWW, "2")},
+			},
+			before: []govultr.DomainRecord{owned},
+		},
+		{
+			name:    "unclaimed record at a name in the file is left alone",
+			desired: []govultr.DomainRecord{newWWW},
+			live:    []govultr.DomainRecord{hand},
+			prune:   true,
+			create:  []govultr.DomainRecord{newWWW, ownerMarker("me", newWWW)},
+			before:  []govultr.DomainRecord{hand},
+		},
+		{
+			name:    "unclaimed record that is in the file is not claimed",
+			desired: []govultr.DomainRecord{{Type: "A", Name: "www", Data: "192.0.2.9", TTL: 60}},
+			live:    []govultr.DomainRecord{hand},
+			prune:   true,
+			before:  []govultr.DomainRecord{hand},
+		},
+		{
+			name:   "prune deletes owned records and their markers only",
+			live:   []govultr.DomainRecord{owned, ownedMarker, hand, elsewhere, theirs, theirMarker},
+			prune:  true,
+			delete: []govultr.DomainRecord{owned, ownedMarker},
+			before: []govultr.DomainRecord{owned},
+		},
+		{
+			name:   "without prune owned records stay",
+			live:   []govultr.DomainRecord{owned, ownedMarker},
+			before: []govultr.DomainRecord{owned},
+		},
+		{
+			name:   "prune deletes markers whose record is gone",
+			live:   []govultr.DomainRecord{ownedMarker, hand},
+			prune:  true,
+			delete: []govultr.DomainRecord{ownedMarker},
+		},
+		{
+			name:    "per name markers claim nothing",
+			desired: []govultr.DomainRecord{newWWW},
+			live:    []govultr.DomainRecord{hand, {ID: "7", Type: "TXT", Name: ownerMarkerName("www"), Data: `"` + ownerMarkerData + `me"`}},
+			prune:   true,
+			create:  []govultr.DomainRecord{newWWW, ownerMarker("me", newWWW)},
+			before:  []govultr.DomainRecord{hand},
+		},
+		{
+			name:    "record claimed by another owner",
+			desired: []govultr.DomainRecord{{Type: "TXT", Name: "", Data: `"v=spf1 -all"`}},
+			live:    []govultr.DomainRecord{theirs, theirMarker},
+			err:     `managed by owner "them"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := planRecordSync("me", tt.desired, tt.live, tt.prune)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("planRecordSync() error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("planRecordSync() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(plan.Create, tt.create) {
+				t.Errorf("create = %+v, want %+v", plan.Create, tt.create)
+			}
+			if !reflect.DeepEqual(plan.Update, tt.update) {
+				t.Errorf("update = %+v, want %+v", plan.Update, tt.update)
+			}
+			if !reflect.DeepEqual(plan.Delete, tt.delete) {
+				t.Errorf("delete = %+v, want %+v", plan.Delete, tt.delete)
+			}
+			if !reflect.DeepEqual(plan.Before, tt.before) {
+				t.Errorf("before = %+v, want %+v", plan.Before, tt.before)
+			}
+		})
+	}
+}
+
+func TestParseOwnerMarker(t *testing.T) {
+	r := govultr.DomainRecord{Type: "A", Name: "www", Data: "192.0.2.1"}
+
+	owner, key := parseOwnerMarker(ownerMarker("team,a", r))
+	if owner != "team,a" || key != ownershipKey(r) {
+		t.Errorf("parseOwnerMarker() = %q, %q, want %q, %q", owner, key, "team,a", ownershipKey(r))
+	}
+
+	other := govultr.DomainRecord{Type: "A", Name: "www", Data: "192.0.2.2"}
+	if ownershipKey(r) == ownershipKey(other) {
+		t.Errorf("records with different data share the ownership key %q", ownershipKey(r))
+	}
+}
+
+func TestRecordSetName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+		err  bool
+	}{
+		{"www", "www", false},
+		{"WWW", "www", false},
+		{"@", "", false},
+		{"", "", false},
+		{"www.example.com.", "www", false},
+		{"www.example.com", "www", false},
+		{"example.com.", "", false},
+		{"www.other.org.", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := recordSetName(tt.name, "example.com")
+		if (err != nil) != tt.err || got != tt.want {
+			t.Errorf("recordSetName(%q) = %q, %v, want %q, error %v", tt.name, got, err, tt.want, tt.err)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+// Copyright © 2019 The Vultr-cli Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/vultr/govultr/v2"
+	"github.com/vultr/vultr-cli/v2/cmd/printer"
+)
+
+var dnsSearch = &cobra.Command{
+	Use:   "search <ip-or-name>",
+	Short: "find the records of every domain that point at an address or host",
+	Long: `search looks through the records of every domain on the account for A and
+AAAA records with the given address, or CNAME, NS, MX and SRV records with the
+given host as their target. Run it before releasing a reserved IP or deleting
+an instance to see which records would be left dangling.`,
+	Example: `
+	vultr-cli dns search 192.0.2.10
+	vultr-cli dns search web1.example.com
+	`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return errors.New("please provide one IP address or host name")
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := context.Background()
+
+		domains, err := listAllDomains(ctx)
+		if err != nil {
+			fmt.Printf("error while getting domains : %v\n", err)
+			os.Exit(1)
+		}
+
+		var matches []printer.DNSRecordMatch
+		for _, d := range domains {
+			records, err := listAllDomainRecords(ctx, d.Domain)
+			if err != nil {
+				fmt.Printf("error while getting dns records of %s : %v\n", d.Domain, err)
+				os.Exit(1)
+			}
+
+			for _, r := range records {
+				if !pointsAt(r, args[0]) {
+					continue
+				}
+				matches = append(matches, printer.DNSRecordMatch{
+					Domain: d.Domain,
+					ID:     r.ID,
+					Type:   r.Type,
+					Name:   recordFQDN(d.Domain, r.Name),
+					Data:   r.Data,
+					TTL:    r.TTL,
+				})
+			}
+		}
+
+		printer.DNSRecordMatches(matches)
+	},
+}
+
+// pointsAt reports whether a record resolves to an address, or names a host
+// as its target
+func pointsAt(r govultr.DomainRecord, target string) bool {
+	if net.ParseIP(target) != nil {
+		return (r.Type == "A" || r.Type == "AAAA") && sameIP(r.Data, target)
+	}
+
+	host := recordTarget(r)
+	return host != "" && host == canonicalName(target)
+}
+
+// recordTarget returns the host a record refers to, or an empty string for
+// types that do not refer to one
+func recordTarget(r govultr.DomainRecord) string {
+	switch r.Type {
+	case "CNAME", "NS", "MX":
+		return canonicalName(r.Data)
+	case "SRV":
+		fields := strings.Fields(r.Data)
+		if len(fields) == 0 {
+			return ""
+		}
+		return canonicalName(fields[len(fields)-1])
+	default:
+		return ""
+	}
+}
+
+// recordFilter holds the --type, --name and --data filters of record list
+type recordFilter struct {
+	rType   string
+	name    string
+	nameSet bool
+	data    string
+}
+
+func newRecordFilter(cmd *cobra.Command) recordFilter {
+	rType, _ := cmd.Flags().GetString("type")
+	name, _ := cmd.Flags().GetString("name")
+	data, _ := cmd.Flags().GetString("data")
+
+	if name == "@" {
+		name = ""
+	}
+	return recordFilter{rType: rType, name: name, nameSet: cmd.Flags().Changed("name"), data: data}
+}
+
+// active reports whether any filter is set. nameSet tells the apex filter,
+// --name @, apart from no name filter.
+func (f recordFilter) active() bool {
+	return f.rType != "" || f.nameSet || f.data != ""
+}
+
+func (f recordFilter) apply(records []govultr.DomainRecord) []govultr.DomainRecord {
+	var matches []govultr.DomainRecord
+	for _, r := range records {
+		if f.match(r) {
+			matches = append(matches, r)
+		}
+	}
+	return matches
+}
+
+func (f recordFilter) match(r govultr.DomainRecord) bool {
+	if f.rType != "" && !strings.EqualFold(r.Type, f.rType) {
+		return false
+	}
+	if f.nameSet && !globMatch(f.name, canonicalName(r.Name)) {
+		return false
+	}
+	if f.data != "" && !dataMatches(r, f.data) {
+		return false
+	}
+	return true
+}
+
+// dataMatches compares record data the way it means rather than the way it
+// is written: addresses by value, hosts without the trailing dot and other
+// data in its normalized form. Patterns with wildcards match the data as
+// stored.
+func dataMatches(r govultr.DomainRecord, pattern string) bool {
+	if strings.ContainsAny(pattern, "*?") {
+		return globMatch(pattern, r.Data)
+	}
+
+	if sameIP(r.Data, pattern) || r.Data == pattern {
+		return true
+	}
+	if host := recordTarget(r); host != "" && host == canonicalName(pattern) {
+		return true
+	}
+
+	data, err := normalizeRecordData(r.Type, pattern)
+	return err == nil && data == comparableData(r)
+}
+
+// globMatch matches s against a pattern where * is any run of characters and
+// ? any single one. Unlike path.Match, * also crosses slashes and dots.
+func globMatch(pattern, s string) bool {
+	expr := regexp.QuoteMeta(strings.ToLower(pattern))
+	expr = strings.ReplaceAll(expr, `\*`, ".*")
+	expr = strings.ReplaceAll(expr, `\?`, ".")
+	return regexp.MustCompile("^" + expr + "$").MatchString(strings.ToLower(s))
+}
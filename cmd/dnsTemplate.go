@@ -0,0 +1,275 @@
+// Copyright © 2019 The Vultr-cli Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/spf13/cobra"
+	"github.com/vultr/govultr/v2"
+	"github.com/vultr/vultr-cli/v2/cmd/printer"
+	"gopkg.in/yaml.v2"
+)
+
+const zoneTemplateHelp = `Templates are YAML files in the dns-templates folder of the vultr-cli config
+directory, ~/.config/vultr-cli/dns-templates on Linux, named after the template.
+They list records in the format of dns record sync. The name and data of each
+record are Go templates, with {{.Domain}}, {{.IP}} and every --var key=value
+as {{.key}}; values are filled in after the YAML is read, so they are never
+read as YAML themselves. Quote fields that start with {{:
+
+	records:
+	  - {name: "@", type: MX, data: aspmx.l.google.com, priority: 1}
+	  - {name: "@", type: TXT, data: "v=spf1 include:_spf.google.com ~all"}
+	  - {name: _dmarc, type: TXT, data: "v=DMARC1; p=quarantine; rua=mailto:dmarc@{{.Domain}}"}
+	  - {name: google._domainkey, type: TXT, data: "{{.DKIM}}"}
+	  - {name: www, type: A, data: "{{.IP}}"}`
+
+var domainApplyTemplate = &cobra.Command{
+	Use:   "apply-template <domainName>",
+	Short: "create the records of a zone template in a domain",
+	Long: `apply-template creates the records of a zone template in an existing domain.
+Records the domain already has are left alone.
+
+` + zoneTemplateHelp,
+	Example: `
+	vultr-cli dns domain apply-template example.com --template mail-google --var DKIM="v=DKIM1; k=rsa; p=..."
+	`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 {
+			return errors.New("please provide a domain name")
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		domain := canonicalName(args[0])
+		name, _ := cmd.Flags().GetString("template")
+		ip, _ := cmd.Flags().GetString("ip")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		vars, err := templateVars(cmd, domain, ip)
+		if err != nil {
+			fmt.Printf("error reading template variables : %v\n", err)
+			os.Exit(1)
+		}
+
+		records, err := loadZoneTemplate(name, vars)
+		if err != nil {
+			fmt.Printf("error loading zone template : %v\n", err)
+			os.Exit(1)
+		}
+
+		changes, failed, err := applyZoneTemplate(context.Background(), domain, records, false, dryRun)
+		if err != nil {
+			fmt.Printf("error while getting dns records : %v\n", err)
+			os.Exit(1)
+		}
+
+		printer.DNSRecordChanges(changes)
+		if failed {
+			os.Exit(1)
+		}
+	},
+}
+
+// zoneTemplateDir is where zone templates are kept
+func zoneTemplateDir() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "vultr-cli", "dns-templates"), nil
+}
+
+// zoneTemplateNames lists the templates in the template directory
+func zoneTemplateNames(dir string) []string {
+	var names []string
+	for _, ext := range []string{".yaml", ".yml"} {
+		matches, _ := filepath.Glob(filepath.Join(dir, "*"+ext))
+		for _, m := range matches {
+			names = append(names, strings.TrimSuffix(filepath.Base(m), ext))
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// loadZoneTemplate renders a zone template into records. A name with a path
+// separator is read as a file, anything else is looked up in the template
+// directory.
+func loadZoneTemplate(name string, vars map[string]string) ([]govultr.DomainRecord, error) {
+	path := name
+	if !strings.ContainsRune(name, os.PathSeparator) {
+		dir, err := zoneTemplateDir()
+		if err != nil {
+			return nil, err
+		}
+
+		path = ""
+		for _, ext := range []string{".yaml", ".yml"} {
+			if _, err := os.Stat(filepath.Join(dir, name+ext)); err == nil {
+				path = filepath.Join(dir, name+ext)
+				break
+			}
+		}
+		if path == "" {
+			available := zoneTemplateNames(dir)
+			if len(available) == 0 {
+				return nil, fmt.Errorf("no template %q, %s has no templates", name, dir)
+			}
+			return nil, fmt.Errorf("no template %q in %s, available are %s", name, dir, strings.Join(available, ", "))
+		}
+	}
+
+	text, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var set recordSet
+	if err := yaml.UnmarshalStrict(text, &set); err != nil {
+		return nil, fmt.Errorf("error reading %s : %v", path, err)
+	}
+
+	for i := range set.Records {
+		s := &set.Records[i]
+		if s.Name, err = renderZoneTemplateField(s.Name, vars); err != nil {
+			return nil, fmt.Errorf("error rendering record %d of %s : %v. Set missing values with --ip or --var", i+1, path, err)
+		}
+		if s.Data, err = renderZoneTemplateField(s.Data, vars); err != nil {
+			return nil, fmt.Errorf("error rendering record %d of %s : %v. Set missing values with --ip or --var", i+1, path, err)
+		}
+	}
+
+	records, err := set.records(vars["Domain"])
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s : %v", path, err)
+	}
+	return records, nil
+}
+
+// renderZoneTemplateField fills the variables into one field of a template
+func renderZoneTemplateField(text string, vars map[string]string) (string, error) {
+	tmpl, err := template.New("").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, vars); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// templateVars collects the template variables. IP is only set when given,
+// so a template that needs it fails instead of writing empty records.
+func templateVars(cmd *cobra.Command, domain, ip string) (map[string]string, error) {
+	vars := map[string]string{"Domain": domain}
+	if ip != "" {
+		vars["IP"] = ip
+	}
+
+	pairs, _ := cmd.Flags().GetStringArray("var")
+	for _, p := range pairs {
+		eq := strings.Index(p, "=")
+		if eq <= 0 {
+			return nil, fmt.Errorf("%q is not key=value", p)
+		}
+		vars[p[:eq]] = p[eq+1:]
+	}
+	return vars, nil
+}
+
+// applyZoneTemplate creates the records a domain does not have yet and
+// reports what happened to each. With replace, the records that
+// templateReplaces picks are deleted first, which clears the default records
+// Vultr creates with a new domain.
+func applyZoneTemplate(ctx context.Context, domain string, records []govultr.DomainRecord, replace, dryRun bool) ([]printer.DNSRecordChange, bool, error) {
+	existing, err := listAllDomainRecords(ctx, domain)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var changes []printer.DNSRecordChange
+	failed := false
+	have := make(map[string]bool, len(existing))
+	for _, r := range existing {
+		if !replace || !templateReplaces(records, r) {
+			have[zoneRecordKey(r)] = true
+			continue
+		}
+
+		change := recordChange("delete", r)
+		if !dryRun {
+			if err := client.DomainRecord.Delete(ctx, domain, r.ID); err != nil {
+				change.Action = fmt.Sprintf("error : %v", err)
+				failed = true
+				have[zoneRecordKey(r)] = true
+			} else {
+				change.Action = "deleted"
+			}
+		}
+		changes = append(changes, change)
+	}
+
+	for _, r := range records {
+		change := recordChange("create", r)
+		switch {
+		case have[zoneRecordKey(r)]:
+			change.Action = "exists"
+		case !dryRun:
+			if _, err := client.DomainRecord.Create(ctx, domain, recordReq(r)); err != nil {
+				change.Action = fmt.Sprintf("error : %v", err)
+				failed = true
+			} else {
+				change.Action = "created"
+			}
+		}
+		have[zoneRecordKey(r)] = true
+		changes = append(changes, change)
+	}
+	return changes, failed, nil
+}
+
+// templateReplaces reports whether a record of the domain gives way to the
+// template: the template has records of the same type and name but not this
+// one. The apex NS records are always kept, as the domain's delegation
+// depends on them.
+func templateReplaces(records []govultr.DomainRecord, r govultr.DomainRecord) bool {
+	if r.Type == "NS" && canonicalName(r.Name) == "" {
+		return false
+	}
+
+	replaced := false
+	for _, t := range records {
+		if zoneRecordKey(t) == zoneRecordKey(r) {
+			return false
+		}
+		if strings.EqualFold(t.Type, r.Type) && canonicalName(t.Name) == canonicalName(r.Name) {
+			replaced = true
+		}
+	}
+	return replaced
+}
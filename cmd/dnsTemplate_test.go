@@ -0,0 +1,327 @@
+// Copyright © 2019 The Vultr-cli Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/vultr/govultr/v2"
+)
+
+func TestTemplateReplaces(t *testing.T) {
+	// The records of the zone template example in zoneTemplateHelp
+	template := []govultr.DomainRecord{
+		{Type: "MX", Name: "", Data: "aspmx.l.google.com", Priority: 1},
+		{Type: "TXT", Name: "", Data: `"v=spf1 include:_spf.google.com ~all"`},
+		{Type: "TXT", Name: "_dmarc", Data: `"v=DMARC1; p=quarantine"`},
+		{Type: "A", Name: "www", Data: "192.0.2.10"},
+	}
+
+	tests := []struct {
+		name   string
+		record govultr.DomainRecord
+		want   bool
+	}{
+		{"apex NS is kept", govultr.DomainRecord{Type: "NS", Name: "", Data: "ns1.vultr.com"}, false},
+		{"apex A is kept", govultr.DomainRecord{Type: "A", Name: "", Data: "192.0.2.10"}, false},
+		{"mail A is kept", govultr.DomainRecord{Type: "A", Name: "mail", Data: "192.0.2.10"}, false},
+		{"www CNAME is kept", govultr.DomainRecord{Type: "CNAME", Name: "www", Data: "example.com"}, false},
+		{"apex MX is replaced", govultr.DomainRecord{Type: "MX", Name: "", Data: "mail.example.com", Priority: 10}, true},
+		{"apex TXT is replaced", govultr.DomainRecord{Type: "TXT", Name: "", Data: `"v=spf1 -all"`}, true},
+		{"other www A is replaced", govultr.DomainRecord{Type: "A", Name: "www", Data: "192.0.2.99"}, true},
+		{"record of the template is kept", govultr.DomainRecord{Type: "A", Name: "www", Data: "192.0.2.10"}, false},
+		{"MX of the template with another priority is replaced", govultr.DomainRecord{Type: "MX", Name: "", Data: "aspmx.l.google.com", Priority: 5}, true},
+		{"TXT at another name is kept", govultr.DomainRecord{Type: "TXT", Name: "google._domainkey", Data: `"v=DKIM1"`}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := templateReplaces(template, tt.record); got != tt.want {
+				t.Errorf("templateReplaces(%s %q %s) = %v, want %v", tt.record.Type, tt.record.Name, tt.record.Data, got, tt.want)
+			}
+		})
+	}
+
+	ns := []govultr.DomainRecord{{Type: "NS", Name: "", Data: "ns1.example.net"}}
+	if templateReplaces(ns, govultr.DomainRecord{Type: "NS", Name: "", Data: "ns1.vultr.com"}) {
+		t.Error("templateReplaces() replaced an apex NS record")
+	}
+}
+
+func TestTemplateVars(t *testing.T) {
+	tests := []struct {
+		name string
+		ip   string
+		vars []string
+		want map[string]string
+		err  bool
+	}{
+		{
+			name: "domain only",
+			want: map[string]string{"Domain": "example.com"},
+		},
+		{
+			name: "ip and vars",
+			ip:   "192.0.2.10",
+			vars: []string{"DKIM=v=DKIM1; k=rsa; p=abc", "Empty="},
+			want: map[string]string{"Domain": "example.com", "IP": "192.0.2.10", "DKIM": "v=DKIM1; k=rsa; p=abc", "Empty": ""},
+		},
+		{
+			name: "not key=value",
+			vars: []string{"DKIM"},
+			err:  true,
+		},
+		{
+			name: "no key",
+			vars: []string{"=value"},
+			err:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &cobra.Command{}
+			cmd.Flags().StringArray("var", nil, "")
+			for _, v := range tt.vars {
+				if err := cmd.Flags().Set("var", v); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := templateVars(cmd, "example.com", tt.ip)
+			if (err != nil) != tt.err {
+				t.Fatalf("templateVars() error = %v, want error %v", err, tt.err)
+			}
+			if !tt.err && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("templateVars() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadZoneTemplate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vultr-cli-templates")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	template := `records:
+  - {name: "@", type: MX, data: aspmx.l.google.com, priority: 1}
+  - {name: google._domainkey, type: TXT, data: "{{.DKIM}}"}
+  - {name: "www.{{.Domain}}.", type: A, data: "{{.IP}}", ttl: 300}
+`
+	path := filepath.Join(dir, "mail.yaml")
+	if err := ioutil.WriteFile(path, []byte(template), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	vars := func(ip, dkim string) map[string]string {
+		v := map[string]string{"Domain": "example.com", "IP": ip}
+		if dkim != "" {
+			v["DKIM"] = dkim
+		}
+		return v
+	}
+
+	tests := []struct {
+		name string
+		vars map[string]string
+		want []govultr.DomainRecord
+		err  string
+	}{
+		{
+			name: "variables are filled in",
+			vars: vars("192.0.2.10", `v=DKIM1; p="abc"`),
+			want: []govultr.DomainRecord{
+				{Type: "MX", Name: "", Data: "aspmx.l.google.com", Priority: 1},
+				{Type: "TXT", Name: "google._domainkey", Data: `"v=DKIM1; p=\"abc\""`},
+				{Type: "A", Name: "www", Data: "192.0.2.10", TTL: 300},
+			},
+		},
+		{
+			name: "missing variable",
+			vars: vars("192.0.2.10", ""),
+			err:  `no entry for key "DKIM"`,
+		},
+		{
+			name: "a value is not read as YAML",
+			vars: vars("192.0.2.10\n  - {name: evil, type: A, data: 203.0.113.1}", "v=DKIM1"),
+			err:  "is not an IPv4 address",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := loadZoneTemplate(path, tt.vars)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("loadZoneTemplate() error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("loadZoneTemplate() = %+v, %v, want %+v", got, err, tt.want)
+			}
+		})
+	}
+
+	// Names without a path separator are looked up in the template folder
+	config := os.Getenv("XDG_CONFIG_HOME")
+	defer os.Setenv("XDG_CONFIG_HOME", config)
+	os.Setenv("XDG_CONFIG_HOME", dir)
+
+	templates := filepath.Join(dir, "vultr-cli", "dns-templates")
+	if err := os.MkdirAll(templates, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(templates, "mail.yml"), []byte(template), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := loadZoneTemplate("mail", vars("192.0.2.10", "v=DKIM1")); err != nil {
+		t.Errorf("loadZoneTemplate(mail) error = %v", err)
+	}
+	if _, err := loadZoneTemplate("web", vars("192.0.2.10", "")); err == nil || !strings.Contains(err.Error(), "available are mail") {
+		t.Errorf("loadZoneTemplate(web) error = %v, want the available templates", err)
+	}
+}
+
+// fakeRecordsAPI serves the records of one domain the way the Vultr API
+// does and remembers the records deleted and created
+type fakeRecordsAPI struct {
+	records []govultr.DomainRecord
+	deleted []string
+	created []govultr.DomainRecordReq
+}
+
+func (f *fakeRecordsAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	const prefix = "/v2/domains/example.com/records"
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == prefix:
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"records": f.records,
+			"meta":    map[string]interface{}{"total": len(f.records), "links": map[string]string{}},
+		})
+	case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, prefix+"/"):
+		f.deleted = append(f.deleted, strings.TrimPrefix(r.URL.Path, prefix+"/"))
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPost && r.URL.Path == prefix:
+		var req govultr.DomainRecordReq
+		json.NewDecoder(r.Body).Decode(&req)
+		f.created = append(f.created, req)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{"record": req})
+	default:
+		http.Error(w, "unexpected request", http.StatusNotFound)
+	}
+}
+
+func TestApplyZoneTemplate(t *testing.T) {
+	// Vultr's defaults for a domain created with --ip
+	defaults := []govultr.DomainRecord{
+		{ID: "ns1", Type: "NS", Name: "", Data: "ns1.vultr.com"},
+		{ID: "ns2", Type: "NS", Name: "", Data: "ns2.vultr.com"},
+		{ID: "apex", Type: "A", Name: "", Data: "192.0.2.10"},
+		{ID: "mail", Type: "A", Name: "mail", Data: "192.0.2.10"},
+		{ID: "www", Type: "CNAME", Name: "www", Data: "example.com"},
+		{ID: "mx", Type: "MX", Name: "", Data: "mail.example.com", Priority: 10},
+	}
+	template := []govultr.DomainRecord{
+		{Type: "MX", Name: "", Data: "aspmx.l.google.com", Priority: 1},
+		{Type: "TXT", Name: "", Data: `"v=spf1 include:_spf.google.com ~all"`},
+		{Type: "A", Name: "", Data: "192.0.2.10"},
+	}
+
+	tests := []struct {
+		name    string
+		replace bool
+		dryRun  bool
+		deleted []string
+		created []string
+		actions []string
+	}{
+		{
+			name:    "replace",
+			replace: true,
+			deleted: []string{"mx"},
+			created: []string{"MX aspmx.l.google.com", `TXT "v=spf1 include:_spf.google.com ~all"`},
+			actions: []string{"deleted", "created", "created", "exists"},
+		},
+		{
+			name:    "keep defaults",
+			created: []string{"MX aspmx.l.google.com", `TXT "v=spf1 include:_spf.google.com ~all"`},
+			actions: []string{"created", "created", "exists"},
+		},
+		{
+			name:    "dry run changes nothing",
+			replace: true,
+			dryRun:  true,
+			actions: []string{"delete", "create", "create", "exists"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := &fakeRecordsAPI{records: defaults}
+			server := httptest.NewServer(api)
+			defer server.Close()
+
+			saved := client
+			defer func() { client = saved }()
+			client = govultr.NewClient(nil)
+			if err := client.SetBaseURL(server.URL); err != nil {
+				t.Fatal(err)
+			}
+
+			changes, failed, err := applyZoneTemplate(context.Background(), "example.com", template, tt.replace, tt.dryRun)
+			if err != nil || failed {
+				t.Fatalf("applyZoneTemplate() failed = %v, error = %v", failed, err)
+			}
+
+			var actions []string
+			for _, c := range changes {
+				actions = append(actions, c.Action)
+			}
+			if !reflect.DeepEqual(actions, tt.actions) {
+				t.Errorf("actions = %v, want %v", actions, tt.actions)
+			}
+
+			if !reflect.DeepEqual(api.deleted, tt.deleted) {
+				t.Errorf("deleted = %v, want %v", api.deleted, tt.deleted)
+			}
+
+			var created []string
+			for _, c := range api.created {
+				created = append(created, c.Type+" "+c.Data)
+			}
+			sort.Strings(created)
+			if !reflect.DeepEqual(created, tt.created) {
+				t.Errorf("created = %v, want %v", created, tt.created)
+			}
+		})
+	}
+}
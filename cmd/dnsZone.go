@@ -0,0 +1,235 @@
+// Copyright © 2019 The Vultr-cli Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/vultr/govultr/v2"
+	"github.com/vultr/vultr-cli/v2/cmd/printer"
+)
+
+var domainImport = &cobra.Command{
+	Use:   "import <domainName>",
+	Short: "import a BIND zone file into a domain",
+	Long: `import reads an RFC 1035 zone file and creates its records in a domain,
+creating the domain first if it does not exist. Records that are already in
+the domain are left alone.
+
+Entries Vultr DNS can not hold, such as other record types, names outside the
+domain or the NS records of the domain itself, are listed at the end. The SOA
+is only applied with --soa, since the one of another provider names its own
+nameservers.`,
+	Example: `
+	# See what would be created
+	vultr-cli dns domain import example.com -f example.com.zone --dry-run
+	`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 {
+			return errors.New("please provide a domain name")
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		domain := canonicalName(args[0])
+		file, _ := cmd.Flags().GetString("file")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		applySOA, _ := cmd.Flags().GetBool("soa")
+
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			fmt.Printf("error reading zone file : %v\n", err)
+			os.Exit(1)
+		}
+
+		zone, err := parseZone(string(data), domain)
+		if err != nil {
+			fmt.Printf("error parsing %s : %v\n", file, err)
+			os.Exit(1)
+		}
+
+		ctx := context.Background()
+		exists, err := domainExists(ctx, domain)
+		if err != nil {
+			fmt.Printf("error getting dns domain : %v\n", err)
+			os.Exit(1)
+		}
+
+		if !exists && !dryRun {
+			if _, err := client.Domain.Create(ctx, &govultr.DomainReq{Domain: domain}); err != nil {
+				fmt.Printf("error creating dns domain : %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("Created domain %s\n", domain)
+		} else if !exists {
+			fmt.Printf("Domain %s would be created\n", domain)
+		}
+
+		var existing []govultr.DomainRecord
+		if exists || !dryRun {
+			if existing, err = listAllDomainRecords(ctx, domain); err != nil {
+				fmt.Printf("error while getting dns records : %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		have := make(map[string]bool, len(existing))
+		for _, r := range existing {
+			have[zoneRecordKey(r)] = true
+		}
+
+		var changes []printer.DNSRecordChange
+		failed := false
+		for _, r := range zone.Records {
+			// Only keep the apex NS records that Vultr already serves, the ones
+			// of the previous provider would break delegation
+			if r.Type == "NS" && r.Name == "" && !have[zoneRecordKey(r)] {
+				zone.Skipped = append(zone.Skipped, printer.ZoneSkipped{Text: fmt.Sprintf("@ NS %s", r.Data), Reason: "NS records of the domain itself are managed by Vultr"})
+				continue
+			}
+
+			change := recordChange("create", r)
+			switch {
+			case have[zoneRecordKey(r)]:
+				change.Action = "exists"
+			case !dryRun:
+				if _, err := client.DomainRecord.Create(ctx, domain, recordReq(r)); err != nil {
+					change.Action = fmt.Sprintf("error : %v", err)
+					failed = true
+				} else {
+					change.Action = "created"
+				}
+			}
+			have[zoneRecordKey(r)] = true
+			changes = append(changes, change)
+		}
+
+		printer.DNSRecordChanges(changes)
+
+		if zone.SOA != nil {
+			switch {
+			case !applySOA:
+				zone.Skipped = append(zone.Skipped, printer.ZoneSkipped{Text: fmt.Sprintf("SOA %s %s", zone.SOA.NSPrimary, zone.SOA.Email), Reason: "SOA is only applied with --soa"})
+			case dryRun:
+				fmt.Printf("SOA would be set to %s %s\n", zone.SOA.NSPrimary, zone.SOA.Email)
+			default:
+				if err := client.Domain.UpdateSoa(ctx, domain, zone.SOA); err != nil {
+					fmt.Printf("error updating SOA : %v\n", err)
+					failed = true
+				}
+			}
+		}
+
+		if len(zone.Skipped) > 0 {
+			fmt.Printf("\n%d entries were not imported\n", len(zone.Skipped))
+			printer.ZoneSkippedList(zone.Skipped)
+		}
+
+		if failed {
+			os.Exit(1)
+		}
+	},
+}
+
+// domainExists reports whether the domain is on the account
+func domainExists(ctx context.Context, domain string) (bool, error) {
+	domains, err := listAllDomains(ctx)
+	if err != nil {
+		return false, err
+	}
+	for _, d := range domains {
+		if canonicalName(d.Domain) == domain {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// recordReq turns a record into a create request. Priority is only sent for
+// the types that have one.
+func recordReq(r govultr.DomainRecord) *govultr.DomainRecordReq {
+	req := &govultr.DomainRecordReq{
+		Name: r.Name,
+		Type: r.Type,
+		Data: r.Data,
+		TTL:  r.TTL,
+	}
+	if r.Type == "MX" || r.Type == "SRV" {
+		req.Priority = govultr.IntToIntPtr(r.Priority)
+	}
+	return req
+}
+
+func recordChange(action string, r govultr.DomainRecord) printer.DNSRecordChange {
+	return printer.DNSRecordChange{
+		Action:   action,
+		Type:     r.Type,
+		Name:     r.Name,
+		Data:     r.Data,
+		Priority: r.Priority,
+		TTL:      r.TTL,
+	}
+}
+
+var domainExport = &cobra.Command{
+	Use:   "export <domainName>",
+	Short: "export a domain as a BIND zone file",
+	Long: `export writes the SOA and every record of a domain as an RFC 1035 zone file,
+which dns domain import reads back into the same records.`,
+	Example: `
+	vultr-cli dns domain export example.com -o example.com.zone
+	`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 {
+			return errors.New("please provide a domain name")
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		domain := args[0]
+		output, _ := cmd.Flags().GetString("output")
+
+		ctx := context.Background()
+		soa, err := client.Domain.GetSoa(ctx, domain)
+		if err != nil {
+			fmt.Printf("error getting soa info : %v\n", err)
+			os.Exit(1)
+		}
+
+		records, err := listAllDomainRecords(ctx, domain)
+		if err != nil {
+			fmt.Printf("error while getting dns records : %v\n", err)
+			os.Exit(1)
+		}
+
+		zone := renderZone(domain, soa, records, time.Now().UTC().Format("20060102")+"00")
+
+		if output == "" {
+			fmt.Print(zone)
+			return
+		}
+
+		if err := writeFileAtomic(output, []byte(zone), 0644); err != nil {
+			fmt.Printf("error writing zone file : %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
@@ -0,0 +1,504 @@
+// Copyright © 2019 The Vultr-cli Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/vultr/vultr-cli/v2/cmd/printer"
+)
+
+const (
+	dnskeyType = 48
+
+	// dnskeySEP marks a key signing key in the DNSKEY flags
+	dnskeySEP = 1
+
+	dnssecOK       = "ok"
+	dnssecMissing  = "missing"
+	dnssecMismatch = "mismatch"
+	dnssecError    = "error"
+)
+
+var secDSRecords = &cobra.Command{
+	Use:   "dnssec-ds-records <domainName>",
+	Short: "print the DS records to give to the registrar",
+	Long: `dnssec-ds-records prints the delegation signer records of a domain with DNSSEC
+enabled, for the registrar to publish in the parent zone.
+
+--format table lists the key tag, algorithm, digest type and digest, the fields
+most registrar forms ask for. --format zone prints DS records as zone file
+lines and --format dnskey prints the key signing keys, for registrars that take
+the key and work out the DS record themselves.`,
+	Example: `
+	vultr-cli dns domain dnssec-ds-records example.com --format zone
+	`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 {
+			return errors.New("please provide a domain name")
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		domain := args[0]
+		format, _ := cmd.Flags().GetString("format")
+
+		keys, ds, err := getDNSSec(domain)
+		if err != nil {
+			fmt.Printf("error getting dnssec info : %v\n", err)
+			os.Exit(1)
+		}
+
+		switch format {
+		case "table":
+			printer.DSRecords(ds)
+		case "zone":
+			for _, d := range ds {
+				fmt.Printf("%s IN DS %d %d %d %s\n", absoluteName(domain), d.KeyTag, d.Algorithm, d.DigestType, d.Digest)
+			}
+		case "dnskey":
+			for _, k := range keys {
+				if k.Flags&dnskeySEP != 0 {
+					fmt.Printf("%s IN DNSKEY %d %d %d %s\n", absoluteName(domain), k.Flags, k.Protocol, k.Algorithm, k.PublicKey)
+				}
+			}
+		default:
+			fmt.Printf("error printing ds records : unknown format %q, use table, zone or dnskey\n", format)
+			os.Exit(1)
+		}
+	},
+}
+
+var secCheck = &cobra.Command{
+	Use:   "dnssec-check <domainName>",
+	Short: "check that the nameservers publish the domain's DNSKEY records",
+	Long: `dnssec-check asks every nameserver of the domain for its DNSKEY records and
+compares them with the keys Vultr holds for the domain. It also checks that the
+DS records Vultr gives out were made from those keys.
+
+Statuses are ok, missing (the nameserver does not publish the key or the DS
+record has no key), mismatch (the digest of a DS record does not match its key)
+and error (the nameserver could not be asked).`,
+	Example: `
+	vultr-cli dns domain dnssec-check example.com --fail-on-mismatch
+	`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 {
+			return errors.New("please provide a domain name")
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		domain := args[0]
+		failOnMismatch, _ := cmd.Flags().GetBool("fail-on-mismatch")
+
+		keys, ds, err := getDNSSec(domain)
+		if err != nil {
+			fmt.Printf("error getting dnssec info : %v\n", err)
+			os.Exit(1)
+		}
+
+		ctx := context.Background()
+		records, err := listAllDomainRecords(ctx, domain)
+		if err != nil {
+			fmt.Printf("error while getting dns records : %v\n", err)
+			os.Exit(1)
+		}
+
+		results := checkDS(domain, keys, ds)
+		for _, ns := range acmeNameservers(records) {
+			results = append(results, checkPublishedKeys(ctx, domain, ns, keys)...)
+		}
+
+		printer.DNSSECCheck(results)
+
+		if failOnMismatch {
+			for _, r := range results {
+				if r.Status != dnssecOK {
+					os.Exit(1)
+				}
+			}
+		}
+	},
+}
+
+// dnskey is the data of a DNSKEY record, with the public key in base64
+type dnskey struct {
+	Flags     int
+	Protocol  int
+	Algorithm int
+	PublicKey string
+}
+
+// getDNSSec reads the keys and DS records Vultr holds for a domain
+func getDNSSec(domain string) ([]dnskey, []printer.DSRecord, error) {
+	info, err := client.Domain.GetDNSSec(context.Background(), domain)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keys, ds, err := parseDNSSec(info)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(keys) == 0 && len(ds) == 0 {
+		return nil, nil, fmt.Errorf("dnssec is not enabled for %s", domain)
+	}
+
+	// Make the DS records from the key signing keys when the API has none
+	if len(ds) == 0 {
+		for _, k := range keys {
+			if k.Flags&dnskeySEP == 0 {
+				continue
+			}
+			d, err := makeDS(domain, k, 2)
+			if err != nil {
+				return nil, nil, err
+			}
+			ds = append(ds, d)
+		}
+	}
+	return keys, ds, nil
+}
+
+// parseDNSSec reads the DNSKEY and DS records the API returns as zone file
+// lines
+func parseDNSSec(info []string) ([]dnskey, []printer.DSRecord, error) {
+	var keys []dnskey
+	var ds []printer.DSRecord
+	for _, line := range info {
+		fields := strings.Fields(line)
+		for i, f := range fields {
+			var err error
+			switch strings.ToUpper(f) {
+			case "DNSKEY":
+				var k dnskey
+				k, err = parseDNSKEY(fields[i+1:])
+				keys = append(keys, k)
+			case "DS":
+				var d printer.DSRecord
+				d, err = parseDS(fields[i+1:])
+				ds = append(ds, d)
+			default:
+				continue
+			}
+			if err != nil {
+				return nil, nil, fmt.Errorf("can not read %q : %v", line, err)
+			}
+			break
+		}
+	}
+	return keys, ds, nil
+}
+
+func parseDNSKEY(fields []string) (dnskey, error) {
+	n, err := atois(fields, 3)
+	if err != nil || len(fields) < 4 {
+		return dnskey{}, errors.New("DNSKEY data is flags protocol algorithm key")
+	}
+	return dnskey{Flags: n[0], Protocol: n[1], Algorithm: n[2], PublicKey: strings.Join(fields[3:], "")}, nil
+}
+
+func parseDS(fields []string) (printer.DSRecord, error) {
+	n, err := atois(fields, 3)
+	if err != nil || len(fields) < 4 {
+		return printer.DSRecord{}, errors.New("DS data is key-tag algorithm digest-type digest")
+	}
+	return printer.DSRecord{KeyTag: n[0], Algorithm: n[1], DigestType: n[2], Digest: strings.ToUpper(strings.Join(fields[3:], ""))}, nil
+}
+
+// atois converts the first n fields to numbers
+func atois(fields []string, n int) ([]int, error) {
+	if len(fields) < n {
+		return nil, errors.New("not enough fields")
+	}
+	numbers := make([]int, n)
+	for i := 0; i < n; i++ {
+		v, err := strconv.Atoi(fields[i])
+		if err != nil {
+			return nil, err
+		}
+		numbers[i] = v
+	}
+	return numbers, nil
+}
+
+// rdata is the wire format of the key, which the key tag and digest are
+// worked out over
+func (k dnskey) rdata() ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(k.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("public key is not base64 : %v", err)
+	}
+	return append([]byte{byte(k.Flags >> 8), byte(k.Flags), byte(k.Protocol), byte(k.Algorithm)}, key...), nil
+}
+
+// keyTag works out the key tag of RFC 4034 appendix B
+func (k dnskey) keyTag() (int, error) {
+	rdata, err := k.rdata()
+	if err != nil {
+		return 0, err
+	}
+
+	var ac uint32
+	for i, b := range rdata {
+		if i&1 == 1 {
+			ac += uint32(b)
+		} else {
+			ac += uint32(b) << 8
+		}
+	}
+	ac += ac >> 16 & 0xffff
+	return int(ac & 0xffff), nil
+}
+
+// makeDS builds the DS record of a key with the given digest type
+func makeDS(domain string, k dnskey, digestType int) (printer.DSRecord, error) {
+	var h hash.Hash
+	switch digestType {
+	case 1:
+		h = sha1.New()
+	case 2:
+		h = sha256.New()
+	case 4:
+		h = sha512.New384()
+	default:
+		return printer.DSRecord{}, fmt.Errorf("unsupported DS digest type %d", digestType)
+	}
+
+	name, err := wireName(domain)
+	if err != nil {
+		return printer.DSRecord{}, err
+	}
+	rdata, err := k.rdata()
+	if err != nil {
+		return printer.DSRecord{}, err
+	}
+	tag, err := k.keyTag()
+	if err != nil {
+		return printer.DSRecord{}, err
+	}
+
+	h.Write(name)
+	h.Write(rdata)
+	return printer.DSRecord{
+		KeyTag:     tag,
+		Algorithm:  k.Algorithm,
+		DigestType: digestType,
+		Digest:     strings.ToUpper(hex.EncodeToString(h.Sum(nil))),
+	}, nil
+}
+
+// checkDS checks that every DS record is the digest of one of the keys
+func checkDS(domain string, keys []dnskey, ds []printer.DSRecord) []printer.DNSSECResult {
+	var results []printer.DNSSECResult
+	for _, d := range ds {
+		r := printer.DNSSECResult{Source: "DS", KeyTag: d.KeyTag, Status: dnssecMissing}
+		for _, k := range keys {
+			tag, err := k.keyTag()
+			if err != nil || tag != d.KeyTag || k.Algorithm != d.Algorithm {
+				continue
+			}
+
+			want, err := makeDS(domain, k, d.DigestType)
+			switch {
+			case err != nil:
+				r.Status, r.Detail = dnssecError, err.Error()
+			case want.Digest == d.Digest:
+				r.Status = dnssecOK
+			default:
+				r.Status, r.Detail = dnssecMismatch, "digest does not match the key"
+			}
+			break
+		}
+		results = append(results, r)
+	}
+	return results
+}
+
+// checkPublishedKeys asks one nameserver for the DNSKEY records of a domain
+// and reports for every key whether it is published
+func checkPublishedKeys(ctx context.Context, domain, nameserver string, keys []dnskey) []printer.DNSSECResult {
+	published, err := queryDNSKEY(ctx, domain, nameserver)
+	if err != nil {
+		return []printer.DNSSECResult{{Source: nameserver, Status: dnssecError, Detail: err.Error()}}
+	}
+
+	var results []printer.DNSSECResult
+	for _, k := range keys {
+		tag, _ := k.keyTag()
+		r := printer.DNSSECResult{Source: nameserver, KeyTag: tag, Status: dnssecMissing}
+		for _, p := range published {
+			if sameKey(k, p) {
+				r.Status = dnssecOK
+				break
+			}
+		}
+		results = append(results, r)
+	}
+	return results
+}
+
+// sameKey compares keys by value, as base64 can be written more than one way
+func sameKey(a, b dnskey) bool {
+	ra, errA := a.rdata()
+	rb, errB := b.rdata()
+	return errA == nil && errB == nil && bytes.Equal(ra, rb)
+}
+
+// wireName writes a domain name in DNS wire format, lower cased as DS
+// digests require
+func wireName(name string) ([]byte, error) {
+	name = canonicalName(name)
+
+	var wire []byte
+	if name != "" {
+		for _, label := range strings.Split(name, ".") {
+			if len(label) == 0 || len(label) > 63 {
+				return nil, fmt.Errorf("%q is not a valid domain name", name)
+			}
+			wire = append(wire, byte(len(label)))
+			wire = append(wire, label...)
+		}
+	}
+	return append(wire, 0), nil
+}
+
+// queryDNSKEY asks a nameserver directly for the DNSKEY records of a name.
+// The standard resolver can not look up DNSKEY records, so the query is
+// written by hand. It goes over TCP so large key sets are not truncated.
+func queryDNSKEY(ctx context.Context, name, nameserver string) ([]dnskey, error) {
+	qname, err := wireName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	id := make([]byte, 2)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	// Header with one question, then the question itself
+	msg := append(id, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0)
+	msg = append(msg, qname...)
+	msg = append(msg, 0, dnskeyType, 0, 1)
+
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(nameserver, "53"))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+	if _, err := conn.Write(append([]byte{byte(len(msg) >> 8), byte(len(msg))}, msg...)); err != nil {
+		return nil, err
+	}
+
+	length := make([]byte, 2)
+	if _, err := io.ReadFull(conn, length); err != nil {
+		return nil, err
+	}
+	resp := make([]byte, binary.BigEndian.Uint16(length))
+	if _, err := io.ReadFull(conn, resp); err != nil {
+		return nil, err
+	}
+
+	return parseDNSKEYResponse(resp, id)
+}
+
+// parseDNSKEYResponse reads the DNSKEY records out of the answer section of
+// a DNS response
+func parseDNSKEYResponse(resp, id []byte) ([]dnskey, error) {
+	malformed := errors.New("malformed DNS response")
+
+	if len(resp) < 12 || !bytes.Equal(resp[:2], id) {
+		return nil, malformed
+	}
+	if rcode := resp[3] & 0x0f; rcode != 0 {
+		return nil, fmt.Errorf("nameserver answered with rcode %d", rcode)
+	}
+	questions := int(binary.BigEndian.Uint16(resp[4:6]))
+	answers := int(binary.BigEndian.Uint16(resp[6:8]))
+
+	off := 12
+	for i := 0; i < questions; i++ {
+		if off = skipName(resp, off); off < 0 || off+4 > len(resp) {
+			return nil, malformed
+		}
+		off += 4
+	}
+
+	var keys []dnskey
+	for i := 0; i < answers; i++ {
+		if off = skipName(resp, off); off < 0 || off+10 > len(resp) {
+			return nil, malformed
+		}
+		rrType := binary.BigEndian.Uint16(resp[off:])
+		rdLength := int(binary.BigEndian.Uint16(resp[off+8:]))
+		off += 10
+		if off+rdLength > len(resp) {
+			return nil, malformed
+		}
+
+		rdata := resp[off : off+rdLength]
+		off += rdLength
+		if rrType != dnskeyType || len(rdata) < 4 {
+			continue
+		}
+
+		keys = append(keys, dnskey{
+			Flags:     int(binary.BigEndian.Uint16(rdata)),
+			Protocol:  int(rdata[2]),
+			Algorithm: int(rdata[3]),
+			PublicKey: base64.StdEncoding.EncodeToString(rdata[4:]),
+		})
+	}
+	return keys, nil
+}
+
+// skipName returns the offset after a possibly compressed name, or -1 when
+// the name runs past the message
+func skipName(msg []byte, off int) int {
+	for off < len(msg) {
+		switch l := int(msg[off]); {
+		case l == 0:
+			return off + 1
+		case l&0xc0 == 0xc0:
+			return off + 2
+		default:
+			off += l + 1
+		}
+	}
+	return -1
+}
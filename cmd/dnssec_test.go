@@ -0,0 +1,173 @@
+// Copyright © 2019 The Vultr-cli Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/vultr/vultr-cli/v2/cmd/printer"
+)
+
+// rfc4034Key is the DNSKEY of dskey.example.com from RFC 4034 section 5.4
+var rfc4034Key = dnskey{
+	Flags:     256,
+	Protocol:  3,
+	Algorithm: 5,
+	PublicKey: "AQOeiiR0GOMYkDshWoSKz9XzfwJr1AYtsmx3TGkJaNXVbfi/2pHm822aJ5iI9BMzNXxeYCmZDRD99WYwYqUSdjMmmAphXdvxegXd/M5+X7OrzKBaMbCVdFLUUh6DhweJBjEVv5f2wwjM9XzcnOf+EPbtG9DMBmADjFDc2w/rljwvFw==",
+}
+
+func TestKeyTag(t *testing.T) {
+	tag, err := rfc4034Key.keyTag()
+	if err != nil || tag != 60485 {
+		t.Errorf("keyTag() = %d, %v, want 60485", tag, err)
+	}
+
+	bad := rfc4034Key
+	bad.PublicKey = "not base64!"
+	if _, err := bad.keyTag(); err == nil {
+		t.Error("keyTag() of a key that is not base64 did not fail")
+	}
+}
+
+func TestMakeDS(t *testing.T) {
+	tests := []struct {
+		digestType int
+		want       printer.DSRecord
+		err        bool
+	}{
+		{
+			// RFC 4034 section 5.4
+			digestType: 1,
+			want:       printer.DSRecord{KeyTag: 60485, Algorithm: 5, DigestType: 1, Digest: "2BB183AF5F22588179A53B0A98631FAD1A292118"},
+		},
+		{
+			// RFC 4509 section 2.3
+			digestType: 2,
+			want:       printer.DSRecord{KeyTag: 60485, Algorithm: 5, DigestType: 2, Digest: "D4B7D520E7BB5F0F67674A0CCEB1E3E0614B93C4F9E99B8383F6A1E4469DA50A"},
+		},
+		{digestType: 3, err: true},
+	}
+
+	for _, tt := range tests {
+		for _, domain := range []string{"dskey.example.com", "DSKEY.example.com."} {
+			got, err := makeDS(domain, rfc4034Key, tt.digestType)
+			if tt.err {
+				if err == nil {
+					t.Errorf("makeDS(%s, %d) did not fail", domain, tt.digestType)
+				}
+				continue
+			}
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("makeDS(%s, %d) = %+v, %v, want %+v", domain, tt.digestType, got, err, tt.want)
+			}
+		}
+	}
+}
+
+// dnskeyResponse builds a DNS response to a DNSKEY query for example.com
+// with the given answers, each a type and rdata. Answer owners point back
+// at the question name.
+func dnskeyResponse(id []byte, rcode byte, answers ...[]byte) []byte {
+	msg := append([]byte{}, id...)
+	msg = append(msg, 0x84, rcode, 0, 1, 0, byte(len(answers)), 0, 0, 0, 0)
+	msg = append(msg, 7, 'e', 'x', 'a', 'm', 'p', 'l', 'e', 3, 'c', 'o', 'm', 0, 0, dnskeyType, 0, 1)
+	for _, a := range answers {
+		msg = append(msg, a...)
+	}
+	return msg
+}
+
+func dnskeyAnswer(rrType byte, rdata []byte) []byte {
+	a := []byte{0xc0, 12, 0, rrType, 0, 1, 0, 0, 0x0e, 0x10, byte(len(rdata) >> 8), byte(len(rdata))}
+	return append(a, rdata...)
+}
+
+func TestParseDNSKEYResponse(t *testing.T) {
+	id := []byte{0x12, 0x34}
+	rdata, err := rfc4034Key.rdata()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rrsig := dnskeyAnswer(46, []byte{0, dnskeyType, 5, 2})
+
+	tests := []struct {
+		name string
+		resp []byte
+		want []dnskey
+		err  string
+	}{
+		{
+			name: "key and signature",
+			resp: dnskeyResponse(id, 0, dnskeyAnswer(dnskeyType, rdata), rrsig),
+			want: []dnskey{rfc4034Key},
+		},
+		{
+			name: "no answers",
+			resp: dnskeyResponse(id, 0),
+		},
+		{
+			name: "other query id",
+			resp: dnskeyResponse([]byte{0x43, 0x21}, 0, dnskeyAnswer(dnskeyType, rdata)),
+			err:  "malformed",
+		},
+		{
+			name: "error rcode",
+			resp: dnskeyResponse(id, 3),
+			err:  "rcode 3",
+		},
+		{
+			name: "truncated answer",
+			resp: func() []byte {
+				resp := dnskeyResponse(id, 0, dnskeyAnswer(dnskeyType, rdata))
+				return resp[:len(resp)-10]
+			}(),
+			err: "malformed",
+		},
+		{
+			name: "short header",
+			resp: id,
+			err:  "malformed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseDNSKEYResponse(tt.resp, id)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("parseDNSKEYResponse() error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseDNSKEYResponse() = %+v, %v, want %+v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestWireName(t *testing.T) {
+	got, err := wireName("Example.COM.")
+	want := []byte("\x07example\x03com\x00")
+	if err != nil || string(got) != string(want) {
+		t.Errorf("wireName() = %q, %v, want %q", got, err, want)
+	}
+
+	if _, err := wireName("a.." + strings.Repeat("x", 64)); err == nil {
+		t.Error("wireName() of an invalid name did not fail")
+	}
+}
@@ -0,0 +1,264 @@
+// Copyright © 2019 The Vultr-cli Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/vultr/govultr/v2"
+)
+
+const (
+	hostTypeInstance  = "instance"
+	hostTypeBareMetal = "bare_metal"
+)
+
+// fleetHost is the common view of an instance or bare metal server used by
+// the commands that operate on the whole account.
+type fleetHost struct {
+	ID         string
+	Type       string
+	Label      string
+	Hostname   string
+	Tag        string
+	Region     string
+	Plan       string
+	Os         string
+	OsID       int
+	Status     string
+	MainIP     string
+	V6MainIP   string
+	InternalIP string
+}
+
+// Name returns the most descriptive name available for the host
+func (h *fleetHost) Name() string {
+	switch {
+	case h.Label != "":
+		return h.Label
+	case h.Hostname != "":
+		return h.Hostname
+	default:
+		return h.ID
+	}
+}
+
+// listAllInstances walks every page of the instance list
+func listAllInstances(ctx context.Context) ([]govultr.Instance, error) {
+	var all []govultr.Instance
+	options := &govultr.ListOptions{PerPage: 100}
+	for {
+		instances, meta, err := client.Instance.List(ctx, options)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, instances...)
+
+		if meta == nil || meta.Links == nil || meta.Links.Next == "" {
+			return all, nil
+		}
+		options.Cursor = meta.Links.Next
+	}
+}
+
+// listAllBareMetal walks every page of the bare metal server list
+func listAllBareMetal(ctx context.Context) ([]govultr.BareMetalServer, error) {
+	var all []govultr.BareMetalServer
+	options := &govultr.ListOptions{PerPage: 100}
+	for {
+		servers, meta, err := client.BareMetalServer.List(ctx, options)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, servers...)
+
+		if meta == nil || meta.Links == nil || meta.Links.Next == "" {
+			return all, nil
+		}
+		options.Cursor = meta.Links.Next
+	}
+}
+
+func instanceHost(i *govultr.Instance) fleetHost {
+	return fleetHost{
+		ID:         i.ID,
+		Type:       hostTypeInstance,
+		Label:      i.Label,
+		Hostname:   i.Hostname,
+		Tag:        i.Tag,
+		Region:     i.Region,
+		Plan:       i.Plan,
+		Os:         i.Os,
+		OsID:       i.OsID,
+		Status:     i.Status,
+		MainIP:     i.MainIP,
+		V6MainIP:   i.V6MainIP,
+		InternalIP: i.InternalIP,
+	}
+}
+
+func bareMetalHost(b *govultr.BareMetalServer) fleetHost {
+	return fleetHost{
+		ID:       b.ID,
+		Type:     hostTypeBareMetal,
+		Label:    b.Label,
+		Tag:      b.Tag,
+		Region:   b.Region,
+		Plan:     b.Plan,
+		Os:       b.Os,
+		OsID:     b.OsID,
+		Status:   b.Status,
+		MainIP:   b.MainIP,
+		V6MainIP: b.V6MainIP,
+	}
+}
+
+// listFleet returns every instance and bare metal server on the account,
+// sorted by name so that repeated runs produce stable output.
+func listFleet(ctx context.Context) ([]fleetHost, error) {
+	instances, err := listAllInstances(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	servers, err := listAllBareMetal(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	hosts := make([]fleetHost, 0, len(instances)+len(servers))
+	for i := range instances {
+		hosts = append(hosts, instanceHost(&instances[i]))
+	}
+	for i := range servers {
+		hosts = append(hosts, bareMetalHost(&servers[i]))
+	}
+
+	sort.SliceStable(hosts, func(i, j int) bool {
+		if hosts[i].Name() == hosts[j].Name() {
+			return hosts[i].ID < hosts[j].ID
+		}
+		return hosts[i].Name() < hosts[j].Name()
+	})
+
+	return hosts, nil
+}
+
+// matchTag reports whether a tag satisfies a selector. An empty selector
+// matches everything and a trailing * matches by prefix.
+func matchTag(tag, selector string) bool {
+	if selector == "" {
+		return true
+	}
+	if strings.HasSuffix(selector, "*") {
+		return strings.HasPrefix(tag, strings.TrimSuffix(selector, "*"))
+	}
+	return tag == selector
+}
+
+// filterHosts keeps the hosts whose tag matches the selector
+func filterHosts(hosts []fleetHost, selector string) []fleetHost {
+	if selector == "" {
+		return hosts
+	}
+
+	var matched []fleetHost
+	for _, h := range hosts {
+		if matchTag(h.Tag, selector) {
+			matched = append(matched, h)
+		}
+	}
+	return matched
+}
+
+var regUUID = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// resolveInstance looks an instance up by ID, label or hostname
+func resolveInstance(ctx context.Context, ref string) (*govultr.Instance, error) {
+	if regUUID.MatchString(ref) {
+		return client.Instance.Get(ctx, ref)
+	}
+
+	instances, err := listAllInstances(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []govultr.Instance
+	for _, i := range instances {
+		if i.Label == ref || i.Hostname == ref {
+			matches = append(matches, i)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no instance found with the label or hostname %q", ref)
+	case 1:
+		return &matches[0], nil
+	default:
+		return nil, fmt.Errorf("%d instances match %q, please use an instanceID", len(matches), ref)
+	}
+}
+
+// resolveBareMetal looks a bare metal server up by ID or label
+func resolveBareMetal(ctx context.Context, ref string) (*govultr.BareMetalServer, error) {
+	if regUUID.MatchString(ref) {
+		return client.BareMetalServer.Get(ctx, ref)
+	}
+
+	servers, err := listAllBareMetal(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []govultr.BareMetalServer
+	for _, b := range servers {
+		if b.Label == ref {
+			matches = append(matches, b)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no bare metal server found with the label %q", ref)
+	case 1:
+		return &matches[0], nil
+	default:
+		return nil, fmt.Errorf("%d bare metal servers match %q, please use a bareMetalID", len(matches), ref)
+	}
+}
+
+// listAllOS walks every page of the operating system list
+func listAllOS(ctx context.Context) ([]govultr.OS, error) {
+	var all []govultr.OS
+	options := &govultr.ListOptions{PerPage: 100}
+	for {
+		list, meta, err := client.OS.List(ctx, options)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, list...)
+
+		if meta == nil || meta.Links == nil || meta.Links.Next == "" {
+			return all, nil
+		}
+		options.Cursor = meta.Links.Next
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/vultr/govultr/v2"
@@ -35,9 +36,10 @@ func Instance() *cobra.Command {
 		Long:  ``,
 	}
 
-	instanceCmd.AddCommand(instanceStart, instanceStop, instanceRestart, instanceReinstall, instanceTag, instanceDelete, instanceLabel, instanceBandwidth, instanceList, instanceInfo, updateFwgGroup, instanceRestore, instanceCreate)
+	instanceCmd.AddCommand(instanceStart, instanceStop, instanceRestart, instanceReinstall, instanceTag, instanceDelete, instanceLabel, instanceBandwidth, instanceList, instanceInfo, updateFwgGroup, instanceRestore, instanceCreate, instanceSSH, instanceNeighbors, instanceAffinityReport, instanceClone)
 
 	instanceReinstall.Flags().StringP("host", "", "", "The hostname to assign to this instance")
+	instanceReinstall.Flags().StringArrayP("userdata-file", "", []string{}, "(optional) file to read userdata from before reinstalling. Can be given several times")
 
 	instanceTag.Flags().StringP("tag", "t", "", "tag you want to set for a given instance")
 	instanceTag.MarkFlagRequired("tag")
@@ -52,12 +54,13 @@ func Instance() *cobra.Command {
 
 	instanceRestore.Flags().StringP("backup", "b", "", "id of backup you wish to restore the instance with")
 	instanceRestore.Flags().StringP("snapshot", "s", "", "id of snapshot you wish to restore the instance with")
+	instanceRestore.Flags().BoolP("interactive", "i", false, "choose from the backups and compatible snapshots of the instance")
+	instanceRestore.Flags().Bool("latest", false, "restore from the most recent backup or compatible snapshot")
+	instanceRestore.Flags().BoolP("yes", "y", false, "restore without asking for confirmation")
 
 	instanceCreate.Flags().StringP("region", "r", "", "region id you wish to have the instance created in")
 	instanceCreate.Flags().StringP("plan", "p", "", "plan id you wish the instance to have")
 	instanceCreate.Flags().IntP("os", "o", 0, "os id you wish the instance to have")
-	instanceCreate.MarkFlagRequired("region")
-	instanceCreate.MarkFlagRequired("plan")
 
 	// Optional Params
 	instanceCreate.Flags().StringP("ipxe", "", "", "if you've selected the 'custom' operating system, this can be set to chainload the specified URL on bootup")
@@ -79,6 +82,27 @@ func Instance() *cobra.Command {
 	instanceCreate.Flags().StringP("host", "", "", "The hostname to assign to this instance")
 	instanceCreate.Flags().StringP("tag", "t", "", "The tag to assign to this instance")
 	instanceCreate.Flags().StringP("firewall-group", "", "", "The firewall group to assign to this instance")
+	instanceCreate.Flags().StringArrayP("userdata-file", "", []string{}, "(optional) file to read userdata from. Can be given several times")
+	instanceCreate.Flags().StringP("file", "f", "", "(optional) YAML spec file to read the instance settings from")
+	instanceCreate.Flags().IntP("count", "", 1, "(optional) number of instances to create")
+	instanceCreate.Flags().StringSliceP("regions", "", []string{}, "(optional) comma separated list of region ids to spread the instances across")
+	instanceCreate.Flags().IntP("parallel", "", 5, "(optional) number of create requests to send at once")
+	instanceCreate.Flags().BoolP("rollback", "", false, "(optional) delete the instances that were created when any of them fails")
+
+	sshFlags(instanceSSH)
+	instanceSSH.Flags().Bool("private", false, "(optional) connect to the internal IP of the instance")
+
+	instanceAffinityReport.Flags().StringP("tag", "t", "", "(optional) only flag conflicts that involve an instance with this tag. A trailing * matches by prefix")
+	instanceAffinityReport.Flags().BoolP("all", "a", false, "(optional) list every shared host group, not only the ones with conflicts")
+	instanceAffinityReport.Flags().Bool("fail-on-conflict", false, "(optional) exit with a non-zero status when conflicts are found")
+
+	instanceClone.Flags().StringP("region", "r", "", "(optional) region id for the clone. Defaults to the region of the source")
+	instanceClone.Flags().StringP("plan", "p", "", "(optional) plan id for the clone. Defaults to the plan of the source")
+	instanceClone.Flags().StringP("label", "l", "", "(optional) label for the clone. Defaults to the label of the source")
+	instanceClone.Flags().StringP("host", "", "", "(optional) hostname for the clone. Defaults to the hostname of the source")
+	instanceClone.Flags().StringArrayP("ssh-keys", "s", []string{}, "(optional) ssh keys you want to assign to the clone")
+	instanceClone.Flags().BoolP("delete-snapshot", "", false, "(optional) delete the intermediate snapshot once the clone is active")
+	instanceClone.Flags().DurationP("timeout", "", time.Hour, "(optional) how long to wait for the snapshot to complete")
 
 	instanceList.Flags().StringP("cursor", "c", "", "(optional) Cursor for paging.")
 	instanceList.Flags().IntP("per-page", "p", 100, "(optional) Number of items requested per page. Default is 100 and Max is 500.")
@@ -135,6 +159,23 @@ func Instance() *cobra.Command {
 	backupCreate.Flags().IntP("hour", "o", 0, "Hour value (0-23). Applicable to crons: 'daily', 'weekly', 'monthly', 'daily_alt_even', 'daily_alt_odd'")
 	backupCreate.Flags().IntP("dow", "w", 0, "Day-of-week value (0-6). Applicable to crons: 'weekly'")
 	backupCreate.Flags().IntP("dom", "m", 0, "Day-of-month value (1-28). Applicable to crons: 'monthly'")
+
+	backupPolicyCmd := &cobra.Command{
+		Use:   "policy",
+		Short: "manage backup schedules across instances",
+		Long:  ``,
+	}
+	backupPolicyCmd.AddCommand(backupPolicyApply)
+	backupPolicyApply.Flags().StringP("tag", "t", "", "only include instances with this tag. A trailing * matches by prefix")
+	backupPolicyApply.MarkFlagRequired("tag")
+	backupPolicyApply.Flags().String("type", "", "type string Backup cron type. Can be one of 'daily', 'weekly', 'monthly', 'daily_alt_even', or 'daily_alt_odd'.")
+	backupPolicyApply.MarkFlagRequired("type")
+	backupPolicyApply.Flags().IntP("hour", "o", 0, "Hour value (0-23) of the first instance")
+	backupPolicyApply.Flags().IntP("dow", "w", 0, "Day-of-week value (0-6). Applicable to crons: 'weekly'")
+	backupPolicyApply.Flags().IntP("dom", "m", 0, "Day-of-month value (1-28). Applicable to crons: 'monthly'")
+	backupPolicyApply.Flags().Int("stagger", 1, "(optional) number of consecutive hours to spread the instances over. By default all are backed up at --hour")
+	backupPolicyApply.Flags().Bool("dry-run", false, "(optional) show the changes without making them")
+	backupCMD.AddCommand(backupPolicyCmd)
 	instanceCmd.AddCommand(backupCMD)
 
 	// IPV4 Subcommands
@@ -174,7 +215,10 @@ func Instance() *cobra.Command {
 		Short: "update/list plans for an instance",
 		Long:  ``,
 	}
-	plansCmd.AddCommand(upgradePlan, upgradePlanList)
+	plansCmd.AddCommand(upgradePlan, upgradePlanList, instancePlanAdvise)
+	instancePlanAdvise.Flags().String("apply", "", "plan id to upgrade to, from the advised plans")
+	instancePlanAdvise.Flags().BoolP("yes", "y", false, "upgrade without asking for confirmation")
+	instancePlanAdvise.Flags().BoolP("wait", "w", false, "wait for the instance to be running on the new plan")
 	upgradePlan.Flags().StringP("plan", "p", "", "plan id that you wish to upgrade to")
 	upgradePlan.MarkFlagRequired("plan")
 	instanceCmd.AddCommand(plansCmd)
@@ -185,7 +229,7 @@ func Instance() *cobra.Command {
 		Short: "commands to handle reverse-dns on an instance",
 		Long:  ``,
 	}
-	reverseCmd.AddCommand(defaultIpv4, listIpv6, deleteIpv6, setIpv4, setIpv6)
+	reverseCmd.AddCommand(defaultIpv4, listIpv6, deleteIpv6, setIpv4, setIpv6, reverseDNSSync, reverseDNSCheck)
 	defaultIpv4.Flags().StringP("ip", "i", "", "iPv4 address used in the reverse DNS update")
 	defaultIpv4.MarkFlagRequired("ip")
 	deleteIpv6.Flags().StringP("ip", "i", "", "ipv6 address you wish to delete")
@@ -200,6 +244,15 @@ func Instance() *cobra.Command {
 	setIpv6.Flags().StringP("entry", "e", "", "reverse dns entry")
 	setIpv6.MarkFlagRequired("ip")
 	setIpv6.MarkFlagRequired("entry")
+
+	reverseDNSSync.Flags().String("template", "", "(optional) Go template for the entry of each IP, such as {{.Hostname}}.example.com")
+	reverseDNSSync.Flags().StringP("file", "f", "", "(optional) YAML file mapping IP addresses to entries")
+	reverseDNSSync.Flags().StringP("tag", "t", "", "(optional) only include instances with this tag. A trailing * matches by prefix")
+	reverseDNSSync.Flags().Bool("dry-run", false, "(optional) show the changes without making them")
+
+	reverseDNSCheck.Flags().StringP("tag", "t", "", "(optional) only include instances with this tag. A trailing * matches by prefix")
+	reverseDNSCheck.Flags().BoolP("all", "a", false, "(optional) also list the entries that are consistent")
+	reverseDNSCheck.Flags().Bool("fail-on-mismatch", false, "(optional) exit with status 1 when an entry does not resolve back to its IP")
 	instanceCmd.AddCommand(reverseCmd)
 
 	userdataCmd := &cobra.Command{
@@ -207,10 +260,12 @@ func Instance() *cobra.Command {
 		Short: "commands to handle userdata on an instance",
 		Long:  ``,
 	}
-	userdataCmd.AddCommand(setUserData, getUserData)
+	userdataCmd.AddCommand(setUserData, getUserData, diffInstanceUserData, editInstanceUserData)
 	setUserData.Flags().StringP("userdata", "d", "/dev/stdin", "file to read userdata from")
 	instanceCmd.AddCommand(userdataCmd)
 
+	instanceCmd.AddCommand(InstanceNetwork())
+
 	return instanceCmd
 }
 
@@ -290,12 +345,50 @@ var instanceReinstall = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		id := args[0]
 		h, _ := cmd.Flags().GetString("host")
+		files, _ := cmd.Flags().GetStringArray("userdata-file")
 
 		hostname := &govultr.ReinstallReq{}
 		if h != "" {
 			hostname = &govultr.ReinstallReq{Hostname: h}
 		}
 
+		// Reinstall does not take user-data, so it is set on the instance first
+		if len(files) > 0 {
+			instance, err := client.Instance.Get(context.Background(), id)
+			if err != nil {
+				fmt.Printf("error getting instance : %v\n", err)
+				os.Exit(1)
+			}
+
+			data := &instanceTemplateData{
+				Index:      1,
+				Count:      1,
+				Region:     instance.Region,
+				Plan:       instance.Plan,
+				ID:         instance.ID,
+				Label:      instance.Label,
+				Hostname:   instance.Hostname,
+				Tag:        instance.Tag,
+				MainIP:     instance.MainIP,
+				V6MainIP:   instance.V6MainIP,
+				InternalIP: instance.InternalIP,
+			}
+			if h != "" {
+				data.Hostname = h
+			}
+
+			userData, err := userDataFromFiles("", files, data)
+			if err != nil {
+				fmt.Printf("error reading user-data : %v\n", err)
+				os.Exit(1)
+			}
+
+			if _, err := client.Instance.Update(context.Background(), id, &govultr.InstanceUpdateReq{UserData: userData}); err != nil {
+				fmt.Printf("error setting user-data : %v\n", err)
+				os.Exit(1)
+			}
+		}
+
 		if _, err := client.Instance.Reinstall(context.Background(), id, hostname); err != nil {
 			fmt.Printf("error reinstalling instance : %v\n", err)
 			os.Exit(1)
@@ -650,11 +743,11 @@ var backupGet = &cobra.Command{
 		id := args[0]
 		info, err := client.Instance.GetBackupSchedule(context.TODO(), id)
 		if err != nil {
-			fmt.Printf("error getting application info : %v\n", err)
+			fmt.Printf("error getting backup schedule : %v\n", err)
 			os.Exit(1)
 		}
 
-		printer.BackupsGet(info)
+		printer.BackupsGet(info, describeBackupSchedule(info.Type, info.Hour, info.Dow, info.Dom), backupNextRun(info))
 	},
 }
 
@@ -676,11 +769,10 @@ var backupCreate = &cobra.Command{
 		dow, _ := cmd.Flags().GetInt("dow")
 		dom, _ := cmd.Flags().GetInt("dom")
 
-		backup := &govultr.BackupScheduleReq{
-			Type: crontType,
-			Hour: govultr.IntToIntPtr(hour),
-			Dow:  govultr.IntToIntPtr(dow),
-			Dom:  dom,
+		backup, err := newBackupScheduleReq(cmd, crontType, hour, dow, dom)
+		if err != nil {
+			fmt.Printf("error creating backup schedule : %v\n", err)
+			os.Exit(1)
 		}
 
 		if err := client.Instance.SetBackupSchedule(context.TODO(), id, backup); err != nil {
@@ -760,7 +852,16 @@ var isoDetach = &cobra.Command{
 var instanceRestore = &cobra.Command{
 	Use:   "restore <instanceID>",
 	Short: "restore instance from backup/snapshot",
-	Long:  ``,
+	Long: `Restore an instance from a backup or snapshot.
+
+With --interactive the backups of the instance and the snapshots with the same
+OS that fit on its disk are listed to choose from, and --latest picks the most
+recent of them. Both ask for confirmation and wait for the instance to be
+active again.
+
+Backups are matched to the instance by its ID, label or main IP in their
+description. When the latest backup names another instance as well, --latest
+lists the candidates and asks, even with --yes.`,
 	Args: func(cmd *cobra.Command, args []string) error {
 		if len(args) < 1 {
 			return errors.New("please provide an instanceID")
@@ -772,8 +873,19 @@ var instanceRestore = &cobra.Command{
 
 		backup, _ := cmd.Flags().GetString("backup")
 		snapshot, _ := cmd.Flags().GetString("snapshot")
+		interactive, _ := cmd.Flags().GetBool("interactive")
+		latest, _ := cmd.Flags().GetBool("latest")
 		options := &govultr.RestoreReq{}
 
+		if interactive || latest {
+			if backup != "" || snapshot != "" || (interactive && latest) {
+				fmt.Println("--interactive and --latest can not be used with each other or with a snapshot or backup")
+				os.Exit(1)
+			}
+			restoreWizard(cmd, id)
+			return
+		}
+
 		if backup == "" && snapshot == "" {
 			fmt.Println("at least one flag must be provided (snapshot or backup)")
 			os.Exit(1)
@@ -1022,105 +1134,81 @@ var setIpv6 = &cobra.Command{
 var instanceCreate = &cobra.Command{
 	Use:   "create",
 	Short: "Create an instance",
-	Long:  ``,
+	Long: `Create one or more instances.
+
+Settings can be read from a YAML spec file with --file. Its keys are the flag
+names of this command, plus regions and count, and flags given on the command
+line override it. With --count, --label and --host are Go templates rendered
+with .Index, .Count, .Region and .Plan, and --regions spreads the instances
+round robin across several regions. A plain --label or --host without a
+template gets the index appended, so --count 3 --label web creates web-1, web-2
+and web-3.
+
+--userdata-file can be given several times. Each file is rendered as a Go
+template that can also use .Label, .Hostname and .Tag, #cloud-config files are
+checked to be valid YAML and several files are sent as one MIME multipart
+message.`,
+	Example: `  vultr-cli instance create --region ewr --plan vc2-1c-1gb --os 387
+  vultr-cli instance create -f web.yaml --count 3 --regions ewr,ord,lax --label "web-{{.Index}}-{{.Region}}"`,
 	Run: func(cmd *cobra.Command, args []string) {
-		region, _ := cmd.Flags().GetString("region")
-		plan, _ := cmd.Flags().GetString("plan")
-		osID, _ := cmd.Flags().GetInt("os")
+		parallel, _ := cmd.Flags().GetInt("parallel")
+		rollback, _ := cmd.Flags().GetBool("rollback")
 
-		// Optional
-		ipxe, _ := cmd.Flags().GetString("ipxe")
-		iso, _ := cmd.Flags().GetString("iso")
-		snapshot, _ := cmd.Flags().GetString("snapshot")
-		script, _ := cmd.Flags().GetString("script-id")
-		ipv6, _ := cmd.Flags().GetBool("ipv6")
-		privateNetwork, _ := cmd.Flags().GetBool("private-network")
-		networks, _ := cmd.Flags().GetStringArray("network")
-		label, _ := cmd.Flags().GetString("label")
-		ssh, _ := cmd.Flags().GetStringArray("ssh-keys")
-		backup, _ := cmd.Flags().GetBool("auto-backup")
-		app, _ := cmd.Flags().GetInt("app")
-		image, _ := cmd.Flags().GetString("image")
-		userData, _ := cmd.Flags().GetString("userdata")
-		notify, _ := cmd.Flags().GetBool("notify")
-		ddos, _ := cmd.Flags().GetBool("ddos")
-		ipv4, _ := cmd.Flags().GetString("reserved-ipv4")
-		host, _ := cmd.Flags().GetString("host")
-		tag, _ := cmd.Flags().GetString("tag")
-		fwg, _ := cmd.Flags().GetString("firewall-group")
-
-		osOptions := map[string]interface{}{"iso_id": iso, "os_id": osID, "app_id": app, "snapshot_id": snapshot, "image_id": image}
-
-		if iso != "" {
-			osOptions["iso_id"] = iso
-		}
-
-		osOption, err := optionCheck(osOptions)
+		spec, err := loadInstanceSpec(cmd)
 		if err != nil {
 			fmt.Printf("error creating instance : %v\n", err)
 			os.Exit(1)
 		}
 
-		opt := &govultr.InstanceCreateReq{
-			Plan:                 plan,
-			Region:               region,
-			IPXEChainURL:         ipxe,
-			ISOID:                iso,
-			SnapshotID:           snapshot,
-			ScriptID:             script,
-			AttachPrivateNetwork: networks,
-			Label:                label,
-			SSHKeys:              ssh,
-			AppID:                app,
-			UserData:             userData,
-			ReservedIPv4:         ipv4,
-			Hostname:             host,
-			Tag:                  tag,
-			FirewallGroupID:      fwg,
-			EnableIPv6:           govultr.BoolToBoolPtr(false),
-			DDOSProtection:       govultr.BoolToBoolPtr(false),
-			ActivationEmail:      govultr.BoolToBoolPtr(false),
-			Backups:              "disabled",
-			EnablePrivateNetwork: govultr.BoolToBoolPtr(false),
-			ImageID:              image,
-		}
-
-		// If no osOptions were selected and osID has a real value then set the osOptions to os_id
-		if osOption == "os_id" && osID != 0 {
-			opt.OsID = osID
-		} else if osOption == "" && osID == 0 {
-			fmt.Printf("error creating instance: an os_id, image_id, snapshot_id, iso_id, or app_id must be provided\n")
+		reqs, err := spec.requests()
+		if err != nil {
+			fmt.Printf("error creating instance : %v\n", err)
 			os.Exit(1)
 		}
 
-		if ipv6 {
-			opt.EnableIPv6 = govultr.BoolToBoolPtr(true)
-		}
-		if ddos {
-			opt.DDOSProtection = govultr.BoolToBoolPtr(true)
-		}
-		if notify {
-			opt.ActivationEmail = govultr.BoolToBoolPtr(true)
-		}
-		if backup {
-			opt.Backups = "enabled"
+		if len(reqs) == 1 {
+			instance, err := client.Instance.Create(context.TODO(), reqs[0])
+			if err != nil {
+				fmt.Printf("error creating instance : %v\n", err)
+				os.Exit(1)
+			}
+
+			printer.Instance(instance)
+			return
 		}
-		if privateNetwork {
-			opt.EnablePrivateNetwork = govultr.BoolToBoolPtr(true)
+
+		results := createInstances(context.TODO(), reqs, parallel)
+
+		var created []govultr.Instance
+		failed := 0
+		for _, r := range results {
+			if r.Err != nil {
+				failed++
+				fmt.Printf("error creating instance %d (%s %s) : %v\n", r.Index, r.Req.Region, r.Req.Label, r.Err)
+				continue
+			}
+			created = append(created, *r.Instance)
 		}
 
-		if userData != "" {
-			opt.UserData = base64.StdEncoding.EncodeToString([]byte(userData))
+		if len(created) > 0 {
+			printer.InstancesCreated(created)
 		}
 
-		//region, plan, osOpt, opt
-		instance, err := client.Instance.Create(context.TODO(), opt)
-		if err != nil {
-			fmt.Printf("error creating instance : %v\n", err)
-			os.Exit(1)
+		if failed == 0 {
+			return
 		}
 
-		printer.Instance(instance)
+		fmt.Printf("%d of %d instances failed to create\n", failed, len(results))
+		if rollback {
+			for _, i := range created {
+				if err := client.Instance.Delete(context.TODO(), i.ID); err != nil {
+					fmt.Printf("error rolling back instance %s : %v\n", i.ID, err)
+					continue
+				}
+				fmt.Printf("Rolled back instance %s\n", i.ID)
+			}
+		}
+		os.Exit(1)
 	},
 }
 
@@ -1175,6 +1263,88 @@ var getUserData = &cobra.Command{
 	},
 }
 
+var diffInstanceUserData = &cobra.Command{
+	Use:   "diff <instanceID> <file>",
+	Short: "Compare the user-data of an instance with a local file",
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 2 {
+			return errors.New("please provide an instanceID and a file")
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		userData, err := client.Instance.GetUserData(context.TODO(), args[0])
+		if err != nil {
+			fmt.Printf("error getting user-data : %v\n", err)
+			os.Exit(1)
+		}
+
+		current, err := decodeUserData(userData)
+		if err != nil {
+			fmt.Printf("%v\n", err)
+			os.Exit(1)
+		}
+
+		diff, err := diffUserData(current, args[1])
+		if err != nil {
+			fmt.Printf("error reading user-data : %v\n", err)
+			os.Exit(1)
+		}
+
+		// Exit like diff(1) so the command can be used in scripts
+		if diff != "" {
+			fmt.Print(diff)
+			os.Exit(1)
+		}
+	},
+}
+
+var editInstanceUserData = &cobra.Command{
+	Use:   "edit <instanceID>",
+	Short: "Edit the user-data of an instance in $EDITOR",
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 {
+			return errors.New("please provide an instanceID")
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		userData, err := client.Instance.GetUserData(context.TODO(), args[0])
+		if err != nil {
+			fmt.Printf("error getting user-data : %v\n", err)
+			os.Exit(1)
+		}
+
+		current, err := decodeUserData(userData)
+		if err != nil {
+			fmt.Printf("%v\n", err)
+			os.Exit(1)
+		}
+
+		edited, changed, err := editUserData(current)
+		if err != nil {
+			fmt.Printf("error editing user-data : %v\n", err)
+			os.Exit(1)
+		}
+
+		if !changed {
+			fmt.Println("User-data unchanged")
+			return
+		}
+
+		options := &govultr.InstanceUpdateReq{
+			UserData: encodeUserData(string(edited)),
+		}
+
+		if _, err := client.Instance.Update(context.TODO(), args[0], options); err != nil {
+			fmt.Printf("error setting user-data : %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Println("Set user-data for instance")
+	},
+}
+
 func optionCheck(options map[string]interface{}) (string, error) {
 	var result []string
 	for k, v := range options {
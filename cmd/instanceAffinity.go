@@ -0,0 +1,210 @@
+// Copyright © 2019 The Vultr-cli Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/vultr/govultr/v2"
+	"github.com/vultr/vultr-cli/v2/cmd/printer"
+)
+
+var instanceNeighbors = &cobra.Command{
+	Use:   "neighbors <instanceID>",
+	Short: "list the instances that share a physical host with an instance",
+	Long:  ``,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 {
+			return errors.New("please provide an instanceID")
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		neighbors, err := client.Instance.GetNeighbors(context.Background(), args[0])
+		if err != nil {
+			fmt.Printf("error getting neighbors : %v\n", err)
+			os.Exit(1)
+		}
+
+		printer.InstanceNeighbors(neighbors)
+	},
+}
+
+var instanceAffinityReport = &cobra.Command{
+	Use:   "affinity-report",
+	Short: "group instances that share physical hosts and flag co-located replicas",
+	Long: `affinity-report asks for the neighbors of every instance and groups the
+instances that run on the same physical host.
+
+A group is flagged when two of its instances share a tag or a role. The role
+of an instance is its label without a trailing index, so etcd-1, etcd-2 and
+etcd3 all have the role etcd. With --tag only clashes that involve at least one
+instance with the tag are flagged.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		tag, _ := cmd.Flags().GetString("tag")
+		all, _ := cmd.Flags().GetBool("all")
+		failOnConflict, _ := cmd.Flags().GetBool("fail-on-conflict")
+
+		ctx := context.Background()
+		instances, err := listAllInstances(ctx)
+		if err != nil {
+			fmt.Printf("error getting list of instances : %v\n", err)
+			os.Exit(1)
+		}
+
+		groups, err := affinityGroups(ctx, instances)
+		if err != nil {
+			fmt.Printf("error getting neighbors : %v\n", err)
+			os.Exit(1)
+		}
+
+		var report []printer.AffinityMember
+		conflicts := 0
+		for n, group := range groups {
+			members := affinityConflicts(n+1, group, tag)
+
+			flagged := false
+			for _, m := range members {
+				if m.Conflict != "" {
+					flagged = true
+				}
+			}
+			if flagged {
+				conflicts++
+			}
+
+			if flagged || all {
+				report = append(report, members...)
+			}
+		}
+
+		if len(report) == 0 {
+			fmt.Println("No co-located replicas found")
+			return
+		}
+
+		printer.AffinityReport(report)
+
+		if failOnConflict && conflicts > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+// affinityGroups groups instances that share a physical host. Groups with a
+// single instance are left out.
+func affinityGroups(ctx context.Context, instances []govultr.Instance) ([][]govultr.Instance, error) {
+	byID := make(map[string]govultr.Instance, len(instances))
+	parent := make(map[string]string, len(instances))
+	for _, i := range instances {
+		byID[i.ID] = i
+		parent[i.ID] = i.ID
+	}
+
+	var find func(string) string
+	find = func(id string) string {
+		if parent[id] != id {
+			parent[id] = find(parent[id])
+		}
+		return parent[id]
+	}
+
+	for _, i := range instances {
+		neighbors, err := client.Instance.GetNeighbors(ctx, i.ID)
+		if err != nil {
+			return nil, fmt.Errorf("instance %s : %v", i.ID, err)
+		}
+
+		for _, n := range neighbors.Neighbors {
+			if _, ok := parent[n]; !ok {
+				continue
+			}
+			parent[find(n)] = find(i.ID)
+		}
+	}
+
+	grouped := map[string][]govultr.Instance{}
+	for _, i := range instances {
+		root := find(i.ID)
+		grouped[root] = append(grouped[root], byID[i.ID])
+	}
+
+	var groups [][]govultr.Instance
+	for _, g := range grouped {
+		if len(g) < 2 {
+			continue
+		}
+		sort.SliceStable(g, func(a, b int) bool { return g[a].Label < g[b].Label })
+		groups = append(groups, g)
+	}
+
+	sort.SliceStable(groups, func(a, b int) bool { return groups[a][0].Label < groups[b][0].Label })
+	return groups, nil
+}
+
+var regRoleIndex = regexp.MustCompile(`[-_.]?[0-9]+$`)
+
+// instanceRole strips the trailing index from a label, e.g. etcd-2 => etcd
+func instanceRole(label string) string {
+	return strings.ToLower(regRoleIndex.ReplaceAllString(label, ""))
+}
+
+// affinityConflicts describes why members of a host group clash with each
+// other. With a tag selector only clashes that involve at least one instance
+// with a matching tag count.
+func affinityConflicts(group int, instances []govultr.Instance, selector string) []printer.AffinityMember {
+	tags := map[string]int{}
+	roles := map[string]int{}
+	selectedRoles := map[string]bool{}
+	for _, i := range instances {
+		if i.Tag != "" {
+			tags[i.Tag]++
+		}
+		if role := instanceRole(i.Label); role != "" {
+			roles[role]++
+			if matchTag(i.Tag, selector) {
+				selectedRoles[role] = true
+			}
+		}
+	}
+
+	members := make([]printer.AffinityMember, 0, len(instances))
+	for _, i := range instances {
+		var reasons []string
+		if i.Tag != "" && tags[i.Tag] > 1 && matchTag(i.Tag, selector) {
+			reasons = append(reasons, fmt.Sprintf("tag %s", i.Tag))
+		}
+		if role := instanceRole(i.Label); role != "" && roles[role] > 1 && selectedRoles[role] {
+			reasons = append(reasons, fmt.Sprintf("role %s", role))
+		}
+
+		members = append(members, printer.AffinityMember{
+			Group:    group,
+			ID:       i.ID,
+			Label:    i.Label,
+			Tag:      i.Tag,
+			Region:   i.Region,
+			Conflict: strings.Join(reasons, ", "),
+		})
+	}
+	return members
+}
@@ -0,0 +1,175 @@
+// Copyright © 2019 The Vultr-cli Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/vultr/govultr/v2"
+	"github.com/vultr/vultr-cli/v2/cmd/printer"
+)
+
+var instanceClone = &cobra.Command{
+	Use:   "clone <instanceID>",
+	Short: "clone an instance through a snapshot",
+	Long: `clone takes a snapshot of an instance, waits for it to complete and creates a
+new instance from it.
+
+The new instance gets the plan, region, firewall group, tag, IPv6, backup and
+DDoS protection settings and private networks of the source. Private networks
+are only carried over when the clone stays in the same region.
+
+The API does not report which SSH keys an instance was created with, so they
+can not be passed on by ID. The keys in the source's authorized_keys files
+come along with the snapshot; --ssh-keys adds account keys on top.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 {
+			return errors.New("please provide an instanceID")
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		region, _ := cmd.Flags().GetString("region")
+		plan, _ := cmd.Flags().GetString("plan")
+		label, _ := cmd.Flags().GetString("label")
+		host, _ := cmd.Flags().GetString("host")
+		sshKeys, _ := cmd.Flags().GetStringArray("ssh-keys")
+		deleteSnapshot, _ := cmd.Flags().GetBool("delete-snapshot")
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+
+		ctx := context.Background()
+		source, err := client.Instance.Get(ctx, args[0])
+		if err != nil {
+			fmt.Printf("error getting instance : %v\n", err)
+			os.Exit(1)
+		}
+
+		networks, err := listAllInstanceNetworks(ctx, source.ID)
+		if err != nil {
+			fmt.Printf("error getting private networks : %v\n", err)
+			os.Exit(1)
+		}
+
+		opt := cloneInstanceReq(source, networks)
+		opt.SSHKeys = sshKeys
+		if plan != "" {
+			opt.Plan = plan
+		}
+		if label != "" {
+			opt.Label = label
+		}
+		if host != "" {
+			opt.Hostname = host
+		}
+		if region != "" && region != source.Region {
+			opt.Region = region
+			if len(opt.AttachPrivateNetwork) > 0 {
+				fmt.Printf("Private networks are region specific and will not be attached in %s\n", region)
+				opt.AttachPrivateNetwork = nil
+			}
+		}
+
+		snapshot, err := client.Snapshot.Create(ctx, &govultr.SnapshotReq{
+			InstanceID:  source.ID,
+			Description: fmt.Sprintf("clone of %s %s", source.Label, time.Now().UTC().Format(time.RFC3339)),
+		})
+		if err != nil {
+			fmt.Printf("error creating snapshot : %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Waiting for snapshot %s to complete\n", snapshot.ID)
+		if err := waitForSnapshot(ctx, snapshot.ID, timeout); err != nil {
+			fmt.Printf("error creating snapshot : %v\n", err)
+			fmt.Printf("The snapshot %s has been kept\n", snapshot.ID)
+			os.Exit(1)
+		}
+
+		opt.SnapshotID = snapshot.ID
+		instance, err := client.Instance.Create(ctx, opt)
+		if err != nil {
+			fmt.Printf("error creating instance : %v\n", err)
+			fmt.Printf("The snapshot %s has been kept\n", snapshot.ID)
+			os.Exit(1)
+		}
+
+		if deleteSnapshot {
+			// The snapshot has to stay until the new instance is restored from it
+			fmt.Printf("Waiting for instance %s to become active\n", instance.ID)
+			if instance, err = waitForInstance(ctx, instance.ID); err != nil {
+				fmt.Printf("error waiting for instance : %v\n", err)
+				fmt.Printf("The snapshot %s has been kept\n", snapshot.ID)
+				os.Exit(1)
+			}
+
+			if err := client.Snapshot.Delete(ctx, snapshot.ID); err != nil {
+				fmt.Printf("error deleting snapshot : %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("Deleted snapshot %s\n", snapshot.ID)
+		}
+
+		printer.Instance(instance)
+	},
+}
+
+// cloneInstanceReq builds a create request that mirrors the source instance
+func cloneInstanceReq(source *govultr.Instance, networks []govultr.PrivateNetwork) *govultr.InstanceCreateReq {
+	opt := &govultr.InstanceCreateReq{
+		Region:          source.Region,
+		Plan:            source.Plan,
+		Label:           source.Label,
+		Hostname:        source.Hostname,
+		Tag:             source.Tag,
+		FirewallGroupID: source.FirewallGroupID,
+		EnableIPv6:      govultr.BoolToBoolPtr(false),
+		DDOSProtection:  govultr.BoolToBoolPtr(false),
+		ActivationEmail: govultr.BoolToBoolPtr(false),
+		Backups:         "disabled",
+	}
+
+	for _, f := range source.Features {
+		switch f {
+		case "ipv6":
+			opt.EnableIPv6 = govultr.BoolToBoolPtr(true)
+		case "auto_backups":
+			opt.Backups = "enabled"
+		case "ddos_protection":
+			opt.DDOSProtection = govultr.BoolToBoolPtr(true)
+		}
+	}
+
+	for _, n := range networks {
+		opt.AttachPrivateNetwork = append(opt.AttachPrivateNetwork, n.NetworkID)
+	}
+
+	return opt
+}
+
+// waitForSnapshot polls a snapshot until it is complete
+func waitForSnapshot(ctx context.Context, id string, timeout time.Duration) error {
+	return waitFor(fmt.Sprintf("snapshot %s to complete", id), timeout, func() (bool, error) {
+		s, err := client.Snapshot.Get(ctx, id)
+		if err != nil {
+			return false, err
+		}
+		return s.Status == "complete", nil
+	})
+}
@@ -0,0 +1,191 @@
+// Copyright © 2019 The Vultr-cli Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/vultr/govultr/v2"
+	"github.com/vultr/vultr-cli/v2/cmd/printer"
+)
+
+// InstanceNetwork represents the instance private network commands
+func InstanceNetwork() *cobra.Command {
+	instanceNetworkCmd := &cobra.Command{
+		Use:   "network",
+		Short: "list/attach/detach private networks on an instance",
+		Long:  ``,
+	}
+
+	instanceNetworkCmd.AddCommand(instanceNetworkList, instanceNetworkAttach, instanceNetworkDetach)
+
+	instanceNetworkList.Flags().StringP("cursor", "c", "", "(optional) Cursor for paging.")
+	instanceNetworkList.Flags().IntP("per-page", "p", 100, "(optional) Number of items requested per page. Default is 100 and Max is 500.")
+
+	instanceNetworkAttach.Flags().StringP("network", "n", "", "id or description of the private network you wish to attach")
+	instanceNetworkAttach.Flags().BoolP("wait", "w", false, "(optional) wait until the network shows up on the instance")
+	instanceNetworkAttach.MarkFlagRequired("network")
+
+	instanceNetworkDetach.Flags().StringP("network", "n", "", "id or description of the private network you wish to detach")
+	instanceNetworkDetach.Flags().BoolP("wait", "w", false, "(optional) wait until the network is gone from the instance")
+	instanceNetworkDetach.MarkFlagRequired("network")
+
+	return instanceNetworkCmd
+}
+
+var instanceNetworkList = &cobra.Command{
+	Use:   "list <instanceID>",
+	Short: "list the private networks attached to an instance",
+	Long:  ``,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 {
+			return errors.New("please provide an instanceID")
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		options := getPaging(cmd)
+		networks, meta, err := client.Instance.ListPrivateNetworks(context.Background(), args[0], options)
+		if err != nil {
+			fmt.Printf("error getting private networks : %v\n", err)
+			os.Exit(1)
+		}
+
+		printer.InstancePrivateNetworks(networks, meta)
+	},
+}
+
+var instanceNetworkAttach = &cobra.Command{
+	Use:   "attach <instanceID>",
+	Short: "attach a private network to an instance",
+	Long:  ``,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 {
+			return errors.New("please provide an instanceID")
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		id := args[0]
+		ref, _ := cmd.Flags().GetString("network")
+		wait, _ := cmd.Flags().GetBool("wait")
+
+		ctx := context.Background()
+		network, err := resolveNetwork(ctx, ref)
+		if err != nil {
+			fmt.Printf("error attaching private network : %v\n", err)
+			os.Exit(1)
+		}
+
+		if err := client.Instance.AttachPrivateNetwork(ctx, id, network.NetworkID); err != nil {
+			fmt.Printf("error attaching private network : %v\n", err)
+			os.Exit(1)
+		}
+
+		if wait {
+			if err := waitForPrivateNetwork(ctx, id, network.NetworkID, true); err != nil {
+				fmt.Printf("error attaching private network : %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		fmt.Printf("Attached private network %s\n", network.NetworkID)
+	},
+}
+
+var instanceNetworkDetach = &cobra.Command{
+	Use:   "detach <instanceID>",
+	Short: "detach a private network from an instance",
+	Long:  ``,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 {
+			return errors.New("please provide an instanceID")
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		id := args[0]
+		ref, _ := cmd.Flags().GetString("network")
+		wait, _ := cmd.Flags().GetBool("wait")
+
+		ctx := context.Background()
+		network, err := resolveNetwork(ctx, ref)
+		if err != nil {
+			fmt.Printf("error detaching private network : %v\n", err)
+			os.Exit(1)
+		}
+
+		if err := client.Instance.DetachPrivateNetwork(ctx, id, network.NetworkID); err != nil {
+			fmt.Printf("error detaching private network : %v\n", err)
+			os.Exit(1)
+		}
+
+		if wait {
+			if err := waitForPrivateNetwork(ctx, id, network.NetworkID, false); err != nil {
+				fmt.Printf("error detaching private network : %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		fmt.Printf("Detached private network %s\n", network.NetworkID)
+	},
+}
+
+// listAllInstanceNetworks walks every page of an instance's private networks
+func listAllInstanceNetworks(ctx context.Context, instanceID string) ([]govultr.PrivateNetwork, error) {
+	var all []govultr.PrivateNetwork
+	options := &govultr.ListOptions{PerPage: 100}
+	for {
+		networks, meta, err := client.Instance.ListPrivateNetworks(ctx, instanceID, options)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, networks...)
+
+		if meta == nil || meta.Links == nil || meta.Links.Next == "" {
+			return all, nil
+		}
+		options.Cursor = meta.Links.Next
+	}
+}
+
+// waitForPrivateNetwork polls the instance until the network is attached or
+// detached.
+func waitForPrivateNetwork(ctx context.Context, instanceID, networkID string, attached bool) error {
+	state := "attach"
+	if !attached {
+		state = "detach"
+	}
+
+	return waitFor(fmt.Sprintf("network %s to %s", networkID, state), waitTimeout, func() (bool, error) {
+		networks, err := listAllInstanceNetworks(ctx, instanceID)
+		if err != nil {
+			return false, err
+		}
+
+		found := false
+		for _, n := range networks {
+			if n.NetworkID == networkID {
+				found = true
+				break
+			}
+		}
+		return found == attached, nil
+	})
+}
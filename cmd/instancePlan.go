@@ -0,0 +1,199 @@
+// Copyright © 2019 The Vultr-cli Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/spf13/cobra"
+	"github.com/vultr/govultr/v2"
+	"github.com/vultr/vultr-cli/v2/cmd/printer"
+)
+
+var instancePlanAdvise = &cobra.Command{
+	Use:   "advise <instanceID>",
+	Short: "compare the plans an instance can upgrade to",
+	Long: `advise lists the plans an instance can upgrade to that are available in its
+region, with their resources and the change in monthly cost.
+
+Pass --apply with one of the listed plans to upgrade to it. You are asked to
+confirm unless --yes is given.`,
+	Example: `
+	# Show the upgrade options
+	vultr-cli instance plan advise <instanceID>
+
+	# Upgrade and wait for the instance to come back
+	vultr-cli instance plan advise <instanceID> --apply vc2-2c-4gb --wait
+	`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 {
+			return errors.New("please provide an instanceID")
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		apply, _ := cmd.Flags().GetString("apply")
+		yes, _ := cmd.Flags().GetBool("yes")
+		wait, _ := cmd.Flags().GetBool("wait")
+
+		ctx := context.Background()
+		instance, err := client.Instance.Get(ctx, args[0])
+		if err != nil {
+			fmt.Printf("error getting instance : %v\n", err)
+			os.Exit(1)
+		}
+
+		upgrades, err := client.Instance.GetUpgrades(ctx, instance.ID)
+		if err != nil {
+			fmt.Printf("error listing available plans : %v\n", err)
+			os.Exit(1)
+		}
+
+		plans, err := listAllPlans(ctx)
+		if err != nil {
+			fmt.Printf("error listing plans : %v\n", err)
+			os.Exit(1)
+		}
+
+		current, advice := planAdvice(instance, upgrades.Plans, plans)
+
+		if apply == "" {
+			printer.PlanAdvice(current, advice)
+			return
+		}
+
+		var target *printer.PlanOption
+		for i := range advice {
+			if advice[i].Plan.ID == apply {
+				target = &advice[i]
+				break
+			}
+		}
+		if target == nil {
+			fmt.Printf("error upgrading plans : %s is not an upgrade available for %s in %s\n", apply, instance.ID, instance.Region)
+			os.Exit(1)
+		}
+
+		if !yes {
+			prompt := fmt.Sprintf("Upgrade %s from %s to %s (%+.2f/month)? The instance will be restarted.", instance.Label, instance.Plan, apply, target.CostDelta)
+			if !confirm(prompt) {
+				fmt.Println("Upgrade cancelled")
+				return
+			}
+		}
+
+		if _, err := client.Instance.Update(ctx, instance.ID, &govultr.InstanceUpdateReq{Plan: apply}); err != nil {
+			fmt.Printf("error upgrading plans : %v\n", err)
+			os.Exit(1)
+		}
+
+		if wait {
+			fmt.Printf("Waiting for instance %s to come back on %s\n", instance.ID, apply)
+			if err := waitForPlan(ctx, instance, target.Plan); err != nil {
+				fmt.Printf("error waiting for instance : %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		fmt.Println("Upgraded plan")
+	},
+}
+
+// listAllPlans pages through every plan
+func listAllPlans(ctx context.Context) ([]govultr.Plan, error) {
+	var plans []govultr.Plan
+	options := &govultr.ListOptions{PerPage: 100}
+	for {
+		list, meta, err := client.Plan.List(ctx, "", options)
+		if err != nil {
+			return nil, err
+		}
+		plans = append(plans, list...)
+
+		if meta == nil || meta.Links == nil || meta.Links.Next == "" {
+			return plans, nil
+		}
+		options.Cursor = meta.Links.Next
+	}
+}
+
+// planAdvice joins the upgrade candidates with the plan details, drops the
+// plans that are not sold in the instance's region and sorts them by price.
+// The instance's current plan is returned separately when it is known.
+func planAdvice(instance *govultr.Instance, candidates []string, plans []govultr.Plan) (*govultr.Plan, []printer.PlanOption) {
+	byID := make(map[string]govultr.Plan, len(plans))
+	for _, p := range plans {
+		byID[p.ID] = p
+	}
+
+	var current *govultr.Plan
+	var cost float32
+	if p, ok := byID[instance.Plan]; ok {
+		current = &p
+		cost = p.MonthlyCost
+	}
+
+	var advice []printer.PlanOption
+	for _, id := range candidates {
+		p, ok := byID[id]
+		if !ok || !planInRegion(p, instance.Region) {
+			continue
+		}
+		advice = append(advice, printer.PlanOption{Plan: p, CostDelta: p.MonthlyCost - cost})
+	}
+
+	sort.SliceStable(advice, func(i, j int) bool {
+		return advice[i].Plan.MonthlyCost < advice[j].Plan.MonthlyCost
+	})
+
+	return current, advice
+}
+
+func planInRegion(p govultr.Plan, region string) bool {
+	for _, l := range p.Locations {
+		if l == region {
+			return true
+		}
+	}
+	return false
+}
+
+// waitForPlan polls an instance until it runs on the new plan and is ready.
+// The API reports the new plan before the resize starts, while the instance
+// is still active, so it is only done once it has been seen going down or
+// already has the new plan's resources.
+func waitForPlan(ctx context.Context, instance *govultr.Instance, plan govultr.Plan) error {
+	resized := false
+	return waitFor(fmt.Sprintf("instance %s to be upgraded to %s", instance.ID, plan.ID), waitTimeout, func() (bool, error) {
+		i, err := client.Instance.Get(ctx, instance.ID)
+		if err != nil {
+			return false, err
+		}
+
+		if !instanceReady(i) {
+			resized = true
+			return false, nil
+		}
+		if i.VCPUCount == plan.VCPUCount && i.RAM == plan.RAM && i.Disk == plan.Disk &&
+			(i.VCPUCount != instance.VCPUCount || i.RAM != instance.RAM || i.Disk != instance.Disk) {
+			resized = true
+		}
+		return i.Plan == plan.ID && resized, nil
+	})
+}
@@ -0,0 +1,296 @@
+// Copyright © 2019 The Vultr-cli Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/vultr/govultr/v2"
+	"github.com/vultr/vultr-cli/v2/cmd/printer"
+)
+
+const (
+	restoreSourceBackup   = "backup"
+	restoreSourceSnapshot = "snapshot"
+
+	// backupMatchAmbiguous marks a backup that could belong to more than one
+	// instance
+	backupMatchAmbiguous = "ambiguous"
+)
+
+// restoreWizard picks a backup or snapshot for an instance, either by asking
+// or by taking the latest one, and restores the instance from it.
+func restoreWizard(cmd *cobra.Command, id string) {
+	latest, _ := cmd.Flags().GetBool("latest")
+	yes, _ := cmd.Flags().GetBool("yes")
+
+	ctx := context.Background()
+	instance, err := client.Instance.Get(ctx, id)
+	if err != nil {
+		fmt.Printf("error getting instance : %v\n", err)
+		os.Exit(1)
+	}
+
+	candidates, err := restoreCandidates(ctx, instance)
+	if err != nil {
+		fmt.Printf("error listing backups and snapshots : %v\n", err)
+		os.Exit(1)
+	}
+
+	if len(candidates) == 0 {
+		fmt.Printf("No backups or compatible snapshots found for %s\n", instance.ID)
+		os.Exit(1)
+	}
+
+	// A backup that other instances could also claim is never picked
+	// without asking
+	var choice printer.RestoreCandidate
+	if latest && candidates[0].Match != backupMatchAmbiguous {
+		choice = candidates[0]
+	} else {
+		if latest {
+			fmt.Printf("The latest backup could belong to another instance as well, pick one to restore from\n")
+			yes = false
+		}
+		printer.RestoreCandidates(candidates)
+		i, ok := choose("Restore from", len(candidates))
+		if !ok {
+			fmt.Println("Restore cancelled")
+			return
+		}
+		choice = candidates[i]
+	}
+
+	if !yes {
+		fmt.Printf("WARNING: everything on %s (%s) written since %s will be lost.\n", instance.Label, instance.ID, choice.DateCreated)
+		if !confirm(fmt.Sprintf("Restore %s from %s %s?", instance.ID, choice.Source, choice.ID)) {
+			fmt.Println("Restore cancelled")
+			return
+		}
+	}
+
+	options := &govultr.RestoreReq{}
+	if choice.Source == restoreSourceSnapshot {
+		options.SnapshotID = choice.ID
+	} else {
+		options.BackupID = choice.ID
+	}
+
+	if err := client.Instance.Restore(ctx, instance.ID, options); err != nil {
+		fmt.Printf("error restoring instance : %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Waiting for instance %s to be restored\n", instance.ID)
+	check := restoreCheck(func() (*govultr.Instance, error) {
+		return client.Instance.Get(ctx, instance.ID)
+	})
+	if err := waitFor(fmt.Sprintf("instance %s to be restored", instance.ID), waitTimeout, check); err != nil {
+		fmt.Printf("error waiting for instance : %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Println("Instance has been restored")
+}
+
+// restoreCheck returns a waitFor check for an instance being restored. Right
+// after the restore request the instance still reports active, so it is only
+// done once it has been seen going down and is ready again.
+func restoreCheck(get func() (*govultr.Instance, error)) func() (bool, error) {
+	restoring := false
+	return func() (bool, error) {
+		i, err := get()
+		if err != nil {
+			return false, err
+		}
+
+		if !instanceReady(i) {
+			restoring = true
+			return false, nil
+		}
+		return restoring, nil
+	}
+}
+
+// restoreCandidates returns the completed backups of an instance and the
+// snapshots of the same OS that fit on its disk, newest first. Backups do
+// not name their instance, so they are matched on the ID, label or main IP in
+// their description, see backupOfInstance.
+func restoreCandidates(ctx context.Context, instance *govultr.Instance) ([]printer.RestoreCandidate, error) {
+	var candidates []printer.RestoreCandidate
+
+	backups, err := listAllBackups(ctx)
+	if err != nil {
+		return nil, err
+	}
+	instances, err := listAllInstances(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range backups {
+		if b.Status != "complete" {
+			continue
+		}
+		if match := backupOfInstance(b, instance, instances); match != "" {
+			candidates = append(candidates, printer.RestoreCandidate{
+				Source:      restoreSourceBackup,
+				ID:          b.ID,
+				DateCreated: b.DateCreated,
+				Size:        int64(b.Size),
+				Description: b.Description,
+				Match:       match,
+			})
+		}
+	}
+
+	snapshots, err := listAllSnapshots(ctx)
+	if err != nil {
+		return nil, err
+	}
+	disk := int64(instance.Disk) * 1024 * 1024 * 1024
+	for _, s := range snapshots {
+		if s.Status != "complete" || s.OsID != instance.OsID {
+			continue
+		}
+		if disk > 0 && int64(s.Size) > disk {
+			continue
+		}
+		candidates = append(candidates, printer.RestoreCandidate{
+			Source:      restoreSourceSnapshot,
+			ID:          s.ID,
+			DateCreated: s.DateCreated,
+			Size:        int64(s.Size),
+			Description: s.Description,
+			Match:       "os",
+		})
+	}
+
+	// The API returns RFC 3339 dates so they sort as strings
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].DateCreated > candidates[j].DateCreated
+	})
+
+	return candidates, nil
+}
+
+// backupOfInstance tells how a backup belongs to an instance: "id" when its
+// description names the instance ID, "label" or "ip" when it names the label
+// or main IP and no other instance, backupMatchAmbiguous when another of the
+// instances is named as well, and an empty string when it does not belong.
+// Values are compared as whole tokens, so web does not match web-2.
+func backupOfInstance(b govultr.Backup, instance *govultr.Instance, instances []govultr.Instance) string {
+	if containsToken(b.Description, instance.ID) {
+		return "id"
+	}
+
+	match := ""
+	switch {
+	case containsToken(b.Description, instance.Label):
+		match = "label"
+	case containsToken(b.Description, instance.MainIP):
+		match = "ip"
+	default:
+		return ""
+	}
+
+	for i := range instances {
+		other := &instances[i]
+		if other.ID == instance.ID {
+			continue
+		}
+		for _, s := range []string{other.ID, other.Label, other.MainIP} {
+			if containsToken(b.Description, s) {
+				return backupMatchAmbiguous
+			}
+		}
+	}
+	return match
+}
+
+// containsToken reports whether s occurs in text with no name or address
+// characters right before or after it. A dot or colon that ends the token
+// is punctuation, as in "backup of web1."
+func containsToken(text, s string) bool {
+	if s == "" {
+		return false
+	}
+
+	for from := 0; ; {
+		i := strings.Index(text[from:], s)
+		if i < 0 {
+			return false
+		}
+		start, end := from+i, from+i+len(s)
+		if (start == 0 || !isTokenChar(text[start-1])) && tokenEnds(text, end) {
+			return true
+		}
+		from = start + 1
+	}
+}
+
+// tokenEnds reports whether a token can end at offset end of text
+func tokenEnds(text string, end int) bool {
+	for end < len(text) && (text[end] == '.' || text[end] == ':') {
+		end++
+	}
+	return end == len(text) || !isTokenChar(text[end])
+}
+
+// isTokenChar is a character that can be part of an ID, label, host name or
+// IP address
+func isTokenChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || isDigit(c) || strings.IndexByte("-_.:", c) >= 0
+}
+
+// listAllBackups walks every page of the backup list
+func listAllBackups(ctx context.Context) ([]govultr.Backup, error) {
+	var all []govultr.Backup
+	options := &govultr.ListOptions{PerPage: 100}
+	for {
+		backups, meta, err := client.Backup.List(ctx, options)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, backups...)
+
+		if meta == nil || meta.Links == nil || meta.Links.Next == "" {
+			return all, nil
+		}
+		options.Cursor = meta.Links.Next
+	}
+}
+
+// listAllSnapshots walks every page of the snapshot list
+func listAllSnapshots(ctx context.Context) ([]govultr.Snapshot, error) {
+	var all []govultr.Snapshot
+	options := &govultr.ListOptions{PerPage: 100}
+	for {
+		snapshots, meta, err := client.Snapshot.List(ctx, options)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, snapshots...)
+
+		if meta == nil || meta.Links == nil || meta.Links.Next == "" {
+			return all, nil
+		}
+		options.Cursor = meta.Links.Next
+	}
+}
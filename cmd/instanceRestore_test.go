@@ -0,0 +1,102 @@
+// Copyright © 2019 The Vultr-cli Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/vultr/govultr/v2"
+)
+
+func TestBackupOfInstance(t *testing.T) {
+	web1 := govultr.Instance{ID: "cb676a46-66fd-4dfb-b839-443f2e6c0b60", Label: "web1", MainIP: "192.0.2.1"}
+	web10 := govultr.Instance{ID: "0e8b3c1a-2f4d-4c5e-9a6b-7d8e9f0a1b2c", Label: "web10", MainIP: "192.0.2.10"}
+	db := govultr.Instance{ID: "5b1e2f3a-4c5d-4e6f-8a9b-0c1d2e3f4a5b", Label: "db", MainIP: "192.0.2.20"}
+	instances := []govultr.Instance{web1, web10, db}
+
+	tests := []struct {
+		name        string
+		description string
+		instance    govultr.Instance
+		want        string
+	}{
+		{"id", "auto backup cb676a46-66fd-4dfb-b839-443f2e6c0b60", web1, "id"},
+		{"id wins over other labels", "web10 moved to cb676a46-66fd-4dfb-b839-443f2e6c0b60", web1, "id"},
+		{"label", "nightly web1 backup", web1, "label"},
+		{"label before punctuation", "backup of web1.", web1, "label"},
+		{"label in parentheses", "nightly (web1)", web1, "label"},
+		{"label is not a prefix", "nightly web10 backup", web1, ""},
+		{"label is not a suffix", "nightly xweb1 backup", web1, ""},
+		{"label is not a host name part", "web1.example.com", web1, ""},
+		{"ip", "backup 192.0.2.1", web1, "ip"},
+		{"ip is not a prefix", "backup 192.0.2.10", web1, ""},
+		{"ip of the longer label", "backup 192.0.2.10", web10, "ip"},
+		{"other instance named too", "web1 and db", web1, backupMatchAmbiguous},
+		{"other instance's ip too", "web1 on 192.0.2.20", web1, backupMatchAmbiguous},
+		{"no match", "manual backup", db, ""},
+		{"empty description", "", db, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := govultr.Backup{ID: "backup", Description: tt.description}
+			instance := tt.instance
+			if got := backupOfInstance(b, &instance, instances); got != tt.want {
+				t.Errorf("backupOfInstance(%q, %s) = %q, want %q", tt.description, tt.instance.Label, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRestoreCheck(t *testing.T) {
+	active := govultr.Instance{Status: "active", ServerStatus: "ok"}
+	locked := govultr.Instance{Status: "active", ServerStatus: "locked"}
+	pending := govultr.Instance{Status: "pending", ServerStatus: "none"}
+
+	tests := []struct {
+		name  string
+		polls []govultr.Instance
+		want  []bool
+	}{
+		{"still active right after the request", []govultr.Instance{active, active}, []bool{false, false}},
+		{"done once seen going down", []govultr.Instance{active, locked, active}, []bool{false, false, true}},
+		{"pending then active", []govultr.Instance{pending, locked, active, active}, []bool{false, false, true, true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := 0
+			check := restoreCheck(func() (*govultr.Instance, error) {
+				i := tt.polls[n]
+				n++
+				return &i, nil
+			})
+			for p, want := range tt.want {
+				done, err := check()
+				if err != nil || done != want {
+					t.Errorf("poll %d = %v, %v, want %v", p, done, err, want)
+				}
+			}
+		})
+	}
+
+	check := restoreCheck(func() (*govultr.Instance, error) {
+		return nil, errors.New("not found")
+	})
+	if _, err := check(); err == nil {
+		t.Error("restoreCheck() did not return the error of the request")
+	}
+}
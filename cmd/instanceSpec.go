@@ -0,0 +1,331 @@
+// Copyright © 2019 The Vultr-cli Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/spf13/cobra"
+	"github.com/vultr/govultr/v2"
+	"gopkg.in/yaml.v2"
+)
+
+// instanceSpec describes the instances to create. The YAML keys match the
+// flags of instance create so a spec file reads like a saved command line.
+type instanceSpec struct {
+	Region         string   `yaml:"region"`
+	Regions        []string `yaml:"regions"`
+	Plan           string   `yaml:"plan"`
+	OsID           int      `yaml:"os"`
+	IPXE           string   `yaml:"ipxe"`
+	ISO            string   `yaml:"iso"`
+	Snapshot       string   `yaml:"snapshot"`
+	ScriptID       string   `yaml:"script-id"`
+	IPv6           bool     `yaml:"ipv6"`
+	PrivateNetwork bool     `yaml:"private-network"`
+	Networks       []string `yaml:"network"`
+	Label          string   `yaml:"label"`
+	SSHKeys        []string `yaml:"ssh-keys"`
+	AutoBackup     bool     `yaml:"auto-backup"`
+	AppID          int      `yaml:"app"`
+	Image          string   `yaml:"image"`
+	UserData       string   `yaml:"userdata"`
+	UserDataFiles  []string `yaml:"userdata-file"`
+	Notify         bool     `yaml:"notify"`
+	DDOS           bool     `yaml:"ddos"`
+	ReservedIPv4   string   `yaml:"reserved-ipv4"`
+	Host           string   `yaml:"host"`
+	Tag            string   `yaml:"tag"`
+	FirewallGroup  string   `yaml:"firewall-group"`
+	Count          int      `yaml:"count"`
+}
+
+// instanceTemplateData is what label, hostname and user-data templates are
+// rendered with. Label and hostname templates only see Index, Count, Region
+// and Plan; the rest is filled in once those are known. IP is only set for
+// reverse DNS templates.
+type instanceTemplateData struct {
+	Index      int
+	Count      int
+	Region     string
+	Plan       string
+	ID         string
+	Label      string
+	Hostname   string
+	Tag        string
+	MainIP     string
+	V6MainIP   string
+	InternalIP string
+	IP         string
+}
+
+// loadInstanceSpec reads a spec file, if one was given, and lays any flags
+// that were set on the command line over it.
+func loadInstanceSpec(cmd *cobra.Command) (*instanceSpec, error) {
+	spec := &instanceSpec{}
+
+	file, _ := cmd.Flags().GetString("file")
+	if file != "" {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if err := yaml.UnmarshalStrict(data, spec); err != nil {
+			return nil, fmt.Errorf("error parsing %s : %v", file, err)
+		}
+	}
+
+	flags := cmd.Flags()
+	changed := flags.Changed
+	if changed("region") {
+		spec.Region, _ = flags.GetString("region")
+	}
+	if changed("regions") {
+		spec.Regions, _ = flags.GetStringSlice("regions")
+	}
+	if changed("plan") {
+		spec.Plan, _ = flags.GetString("plan")
+	}
+	if changed("os") {
+		spec.OsID, _ = flags.GetInt("os")
+	}
+	if changed("ipxe") {
+		spec.IPXE, _ = flags.GetString("ipxe")
+	}
+	if changed("iso") {
+		spec.ISO, _ = flags.GetString("iso")
+	}
+	if changed("snapshot") {
+		spec.Snapshot, _ = flags.GetString("snapshot")
+	}
+	if changed("script-id") {
+		spec.ScriptID, _ = flags.GetString("script-id")
+	}
+	if changed("ipv6") {
+		spec.IPv6, _ = flags.GetBool("ipv6")
+	}
+	if changed("private-network") {
+		spec.PrivateNetwork, _ = flags.GetBool("private-network")
+	}
+	if changed("network") {
+		spec.Networks, _ = flags.GetStringArray("network")
+	}
+	if changed("label") {
+		spec.Label, _ = flags.GetString("label")
+	}
+	if changed("ssh-keys") {
+		spec.SSHKeys, _ = flags.GetStringArray("ssh-keys")
+	}
+	if changed("auto-backup") {
+		spec.AutoBackup, _ = flags.GetBool("auto-backup")
+	}
+	if changed("app") {
+		spec.AppID, _ = flags.GetInt("app")
+	}
+	if changed("image") {
+		spec.Image, _ = flags.GetString("image")
+	}
+	if changed("userdata") {
+		spec.UserData, _ = flags.GetString("userdata")
+	}
+	if changed("userdata-file") {
+		spec.UserDataFiles, _ = flags.GetStringArray("userdata-file")
+	}
+	if changed("notify") {
+		spec.Notify, _ = flags.GetBool("notify")
+	}
+	if changed("ddos") {
+		spec.DDOS, _ = flags.GetBool("ddos")
+	}
+	if changed("reserved-ipv4") {
+		spec.ReservedIPv4, _ = flags.GetString("reserved-ipv4")
+	}
+	if changed("host") {
+		spec.Host, _ = flags.GetString("host")
+	}
+	if changed("tag") {
+		spec.Tag, _ = flags.GetString("tag")
+	}
+	if changed("firewall-group") {
+		spec.FirewallGroup, _ = flags.GetString("firewall-group")
+	}
+	if changed("count") {
+		spec.Count, _ = flags.GetInt("count")
+	}
+
+	if spec.Count == 0 {
+		spec.Count = 1
+	}
+
+	return spec, spec.validate()
+}
+
+func (s *instanceSpec) validate() error {
+	if s.Region == "" && len(s.Regions) == 0 {
+		return errors.New("a region must be provided")
+	}
+	if s.Plan == "" {
+		return errors.New("a plan must be provided")
+	}
+	if s.Count < 1 {
+		return errors.New("count must be at least 1")
+	}
+	if s.Count > 1 && s.ReservedIPv4 != "" {
+		return errors.New("a reserved IPv4 can only be used when creating a single instance")
+	}
+	return nil
+}
+
+// region spreads the instances round robin across the regions
+func (s *instanceSpec) region(index int) string {
+	if len(s.Regions) == 0 {
+		return s.Region
+	}
+	return s.Regions[(index-1)%len(s.Regions)]
+}
+
+// renderNameTemplate renders a label or hostname template such as
+// web-{{.Index}}-{{.Region}}. A plain name used for several instances gets
+// the index appended, so they do not all share it.
+func renderNameTemplate(name, text string, data *instanceTemplateData) (string, error) {
+	if !strings.Contains(text, "{{") {
+		if text != "" && data.Count > 1 {
+			return fmt.Sprintf("%s-%d", text, data.Index), nil
+		}
+		return text, nil
+	}
+
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("error parsing %s template : %v", name, err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("error rendering %s template : %v", name, err)
+	}
+	return buf.String(), nil
+}
+
+// requests builds one create request per instance in the spec
+func (s *instanceSpec) requests() ([]*govultr.InstanceCreateReq, error) {
+	osOptions := map[string]interface{}{"iso_id": s.ISO, "os_id": s.OsID, "app_id": s.AppID, "snapshot_id": s.Snapshot, "image_id": s.Image}
+	osOption, err := optionCheck(osOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	// If no osOptions were selected and osID has a real value then set the osOptions to os_id
+	if osOption == "" && s.OsID == 0 {
+		return nil, errors.New("an os_id, image_id, snapshot_id, iso_id, or app_id must be provided")
+	}
+
+	reqs := make([]*govultr.InstanceCreateReq, 0, s.Count)
+	for index := 1; index <= s.Count; index++ {
+		data := &instanceTemplateData{Index: index, Count: s.Count, Region: s.region(index), Plan: s.Plan}
+
+		label, err := renderNameTemplate("label", s.Label, data)
+		if err != nil {
+			return nil, err
+		}
+		host, err := renderNameTemplate("host", s.Host, data)
+		if err != nil {
+			return nil, err
+		}
+
+		data.Label, data.Hostname, data.Tag = label, host, s.Tag
+		userData, err := userDataFromFiles(s.UserData, s.UserDataFiles, data)
+		if err != nil {
+			return nil, err
+		}
+
+		opt := &govultr.InstanceCreateReq{
+			Plan:                 s.Plan,
+			Region:               data.Region,
+			IPXEChainURL:         s.IPXE,
+			ISOID:                s.ISO,
+			SnapshotID:           s.Snapshot,
+			ScriptID:             s.ScriptID,
+			AttachPrivateNetwork: s.Networks,
+			Label:                label,
+			SSHKeys:              s.SSHKeys,
+			AppID:                s.AppID,
+			ReservedIPv4:         s.ReservedIPv4,
+			Hostname:             host,
+			Tag:                  s.Tag,
+			FirewallGroupID:      s.FirewallGroup,
+			EnableIPv6:           govultr.BoolToBoolPtr(s.IPv6),
+			DDOSProtection:       govultr.BoolToBoolPtr(s.DDOS),
+			ActivationEmail:      govultr.BoolToBoolPtr(s.Notify),
+			Backups:              "disabled",
+			EnablePrivateNetwork: govultr.BoolToBoolPtr(s.PrivateNetwork),
+			ImageID:              s.Image,
+			UserData:             userData,
+		}
+
+		if osOption == "os_id" && s.OsID != 0 {
+			opt.OsID = s.OsID
+		}
+		if s.AutoBackup {
+			opt.Backups = "enabled"
+		}
+
+		reqs = append(reqs, opt)
+	}
+
+	return reqs, nil
+}
+
+// instanceCreateResult is the outcome of one create request
+type instanceCreateResult struct {
+	Index    int
+	Req      *govultr.InstanceCreateReq
+	Instance *govultr.Instance
+	Err      error
+}
+
+// createInstances sends the create requests with at most limit in flight
+// and returns the results in request order.
+func createInstances(ctx context.Context, reqs []*govultr.InstanceCreateReq, limit int) []instanceCreateResult {
+	if limit < 1 {
+		limit = 1
+	}
+
+	results := make([]instanceCreateResult, len(reqs))
+	sem := make(chan struct{}, limit)
+
+	var wg sync.WaitGroup
+	for i, req := range reqs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, req *govultr.InstanceCreateReq) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			instance, err := client.Instance.Create(ctx, req)
+			results[i] = instanceCreateResult{Index: i + 1, Req: req, Instance: instance, Err: err}
+		}(i, req)
+	}
+
+	wg.Wait()
+	return results
+}
@@ -0,0 +1,58 @@
+// Copyright © 2019 The Vultr-cli Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestInstanceSpecRequestNames(t *testing.T) {
+	tests := []struct {
+		name   string
+		count  int
+		label  string
+		host   string
+		labels []string
+		hosts  []string
+	}{
+		{"single instance keeps the name", 1, "web", "web.example.com", []string{"web"}, []string{"web.example.com"}},
+		{"plain names get the index", 3, "web", "web", []string{"web-1", "web-2", "web-3"}, []string{"web-1", "web-2", "web-3"}},
+		{"templates are rendered", 2, "web-{{.Index}}-{{.Region}}", "", []string{"web-1-ewr", "web-2-ewr"}, []string{"", ""}},
+		{"no label", 2, "", "", []string{"", ""}, []string{"", ""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := &instanceSpec{Region: "ewr", Plan: "vc2-1c-1gb", OsID: 387, Count: tt.count, Label: tt.label, Host: tt.host}
+			reqs, err := spec.requests()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var labels, hosts []string
+			for _, r := range reqs {
+				labels = append(labels, r.Label)
+				hosts = append(hosts, r.Hostname)
+			}
+			if !reflect.DeepEqual(labels, tt.labels) {
+				t.Errorf("labels = %v, want %v", labels, tt.labels)
+			}
+			if !reflect.DeepEqual(hosts, tt.hosts) {
+				t.Errorf("hosts = %v, want %v", hosts, tt.hosts)
+			}
+		})
+	}
+}
@@ -0,0 +1,304 @@
+// Copyright © 2019 The Vultr-cli Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	inventoryFormatPrometheus = "prometheus-sd"
+	inventoryFormatHosts      = "hosts"
+	inventoryFormatSSHConfig  = "ssh-config"
+)
+
+// Inventory represents the inventory command
+func Inventory() *cobra.Command {
+	inventoryCmd := &cobra.Command{
+		Use:   "inventory",
+		Short: "generate target lists for monitoring and name resolution",
+		Long: `inventory renders every instance and bare metal server on the account as a
+Prometheus file_sd_config, an /etc/hosts snippet or an ssh_config snippet.
+
+With --watch the output file is rewritten atomically whenever the rendered
+inventory changes.`,
+		Run: inventoryRun,
+	}
+
+	inventoryCmd.Flags().StringP("format", "f", inventoryFormatPrometheus, "output format : Possible values prometheus-sd, hosts, ssh-config")
+	inventoryCmd.Flags().StringP("output", "o", "", "(optional) file to write to. Defaults to stdout")
+	inventoryCmd.Flags().StringP("tag", "t", "", "(optional) only include servers with this tag. A trailing * matches by prefix")
+	inventoryCmd.Flags().IntP("port", "p", 9100, "(optional) port appended to prometheus targets")
+	inventoryCmd.Flags().Bool("private", false, "(optional) use the internal IP of instances when one is assigned")
+	inventoryCmd.Flags().BoolP("watch", "w", false, "(optional) keep running and rewrite the output file whenever the inventory changes")
+	inventoryCmd.Flags().DurationP("interval", "i", time.Minute, "(optional) how often to poll the API in watch mode")
+
+	return inventoryCmd
+}
+
+type inventoryOptions struct {
+	format  string
+	tag     string
+	port    int
+	private bool
+}
+
+func inventoryRun(cmd *cobra.Command, args []string) {
+	format, _ := cmd.Flags().GetString("format")
+	output, _ := cmd.Flags().GetString("output")
+	tag, _ := cmd.Flags().GetString("tag")
+	port, _ := cmd.Flags().GetInt("port")
+	private, _ := cmd.Flags().GetBool("private")
+	watch, _ := cmd.Flags().GetBool("watch")
+	interval, _ := cmd.Flags().GetDuration("interval")
+
+	opts := &inventoryOptions{format: format, tag: tag, port: port, private: private}
+
+	switch format {
+	case inventoryFormatPrometheus, inventoryFormatHosts, inventoryFormatSSHConfig:
+	default:
+		fmt.Printf("error generating inventory : unknown format %q\n", format)
+		os.Exit(1)
+	}
+
+	if watch && output == "" {
+		fmt.Println("error generating inventory : --watch requires --output")
+		os.Exit(1)
+	}
+	if watch && interval <= 0 {
+		fmt.Println("error generating inventory : --interval must be greater than zero")
+		os.Exit(1)
+	}
+
+	data, err := renderInventory(context.Background(), opts)
+	if err != nil {
+		fmt.Printf("error generating inventory : %v\n", err)
+		os.Exit(1)
+	}
+
+	if output == "" {
+		os.Stdout.Write(data)
+		return
+	}
+
+	if err := writeFileAtomic(output, data, 0644); err != nil {
+		fmt.Printf("error writing inventory : %v\n", err)
+		os.Exit(1)
+	}
+
+	if !watch {
+		return
+	}
+
+	log.Printf("wrote inventory to %s, polling every %s", output, interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		next, err := renderInventory(context.Background(), opts)
+		if err != nil {
+			// Keep the last good file in place and try again on the next tick
+			log.Printf("error generating inventory : %v", err)
+			continue
+		}
+
+		if bytes.Equal(next, data) {
+			continue
+		}
+
+		if err := writeFileAtomic(output, next, 0644); err != nil {
+			log.Printf("error writing inventory : %v", err)
+			continue
+		}
+
+		data = next
+		log.Printf("inventory changed, rewrote %s", output)
+	}
+}
+
+func renderInventory(ctx context.Context, opts *inventoryOptions) ([]byte, error) {
+	hosts, err := listFleet(ctx)
+	if err != nil {
+		return nil, err
+	}
+	hosts = filterHosts(hosts, opts.tag)
+
+	switch opts.format {
+	case inventoryFormatHosts:
+		return renderHosts(hosts, opts.private), nil
+	case inventoryFormatSSHConfig:
+		families, err := osFamilies(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return renderSSHConfig(hosts, &sshConfigOptions{private: opts.private, families: families}), nil
+	default:
+		return renderPrometheusSD(hosts, opts.port, opts.private)
+	}
+}
+
+// inventoryAddress picks the address a host should be reached on
+func inventoryAddress(h *fleetHost, private bool) string {
+	if private && h.InternalIP != "" {
+		return h.InternalIP
+	}
+	if h.MainIP != "" && h.MainIP != "0.0.0.0" {
+		return h.MainIP
+	}
+	return h.V6MainIP
+}
+
+type prometheusTargetGroup struct {
+	Targets []string          `json:"targets"`
+	Labels  map[string]string `json:"labels"`
+}
+
+func renderPrometheusSD(hosts []fleetHost, port int, private bool) ([]byte, error) {
+	groups := []prometheusTargetGroup{}
+	for i := range hosts {
+		h := &hosts[i]
+		address := inventoryAddress(h, private)
+		if address == "" {
+			continue
+		}
+
+		groups = append(groups, prometheusTargetGroup{
+			Targets: []string{net.JoinHostPort(address, strconv.Itoa(port))},
+			Labels: map[string]string{
+				"__meta_vultr_id":          h.ID,
+				"__meta_vultr_type":        h.Type,
+				"__meta_vultr_label":       h.Label,
+				"__meta_vultr_hostname":    h.Hostname,
+				"__meta_vultr_tag":         h.Tag,
+				"__meta_vultr_region":      h.Region,
+				"__meta_vultr_plan":        h.Plan,
+				"__meta_vultr_os":          h.Os,
+				"__meta_vultr_status":      h.Status,
+				"__meta_vultr_main_ip":     h.MainIP,
+				"__meta_vultr_v6_main_ip":  h.V6MainIP,
+				"__meta_vultr_internal_ip": h.InternalIP,
+			},
+		})
+	}
+
+	data, err := json.MarshalIndent(groups, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+var regInvalidHostChars = regexp.MustCompile(`[^a-z0-9.-]+`)
+
+// hostAlias turns a label into something usable as a host name
+func hostAlias(h *fleetHost) string {
+	alias := regInvalidHostChars.ReplaceAllString(strings.ToLower(h.Name()), "-")
+	alias = strings.Trim(alias, "-.")
+	if alias == "" {
+		return h.ID
+	}
+	return alias
+}
+
+func renderHosts(hosts []fleetHost, private bool) []byte {
+	var buf bytes.Buffer
+	for i := range hosts {
+		h := &hosts[i]
+		address := inventoryAddress(h, private)
+		if address == "" {
+			continue
+		}
+
+		names := []string{hostAlias(h)}
+		if h.Hostname != "" && h.Hostname != names[0] {
+			names = append(names, h.Hostname)
+		}
+		fmt.Fprintf(&buf, "%s\t%s\n", address, strings.Join(names, " "))
+	}
+	return buf.Bytes()
+}
+
+// writeFileAtomic writes data next to path and renames it into place so
+// readers never observe a partially written file. A symlink at path is
+// followed, so the file it points at is replaced rather than the link.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	if path == "" {
+		return errors.New("no file name given")
+	}
+
+	path, err := resolveSymlink(path)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// resolveSymlink follows the symlinks at path, including one to a file that
+// does not exist yet
+func resolveSymlink(path string) (string, error) {
+	for i := 0; i < 40; i++ {
+		info, err := os.Lstat(path)
+		if os.IsNotExist(err) || (err == nil && info.Mode()&os.ModeSymlink == 0) {
+			return path, nil
+		}
+		if err != nil {
+			return "", err
+		}
+
+		target, err := os.Readlink(path)
+		if err != nil {
+			return "", err
+		}
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(path), target)
+		}
+		path = target
+	}
+	return "", fmt.Errorf("too many levels of symbolic links at %s", path)
+}
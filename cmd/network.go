@@ -19,6 +19,8 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"sort"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/vultr/govultr/v2"
@@ -33,7 +35,7 @@ func Network() *cobra.Command {
 		Long:  ``,
 	}
 
-	networkCmd.AddCommand(networkGet, networkList, networkDelete, networkCreate)
+	networkCmd.AddCommand(networkGet, networkList, networkDelete, networkCreate, networkUpdate, networkShow, networkTopology)
 	networkCreate.Flags().StringP("region-id", "r", "", "id of the region you wish to create the network")
 	networkCreate.Flags().StringP("description", "d", "", "description of the network")
 	networkCreate.Flags().StringP("subnet", "s", "", "The IPv4 network in CIDR notation.")
@@ -46,6 +48,13 @@ func Network() *cobra.Command {
 	networkList.Flags().StringP("cursor", "c", "", "(optional) Cursor for paging.")
 	networkList.Flags().IntP("per-page", "p", 100, "(optional) Number of items requested per page. Default is 100 and Max is 500.")
 
+	networkUpdate.Flags().StringP("description", "d", "", "new description of the network")
+	networkUpdate.MarkFlagRequired("description")
+
+	networkShow.Flags().BoolP("members", "m", false, "(optional) also list the instances attached to the network")
+
+	networkTopology.Flags().StringP("format", "f", "tree", "(optional) output format : Possible values tree, dot")
+
 	return networkCmd
 }
 
@@ -135,3 +144,240 @@ var networkCreate = &cobra.Command{
 		printer.Network(network)
 	},
 }
+
+var networkUpdate = &cobra.Command{
+	Use:   "update <networkID>",
+	Short: "update the description of a private network",
+	Long:  ``,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 {
+			return errors.New("please provide a networkID")
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		id := args[0]
+		description, _ := cmd.Flags().GetString("description")
+
+		if err := client.Network.Update(context.Background(), id, description); err != nil {
+			fmt.Printf("error updating network : %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Println("Updated network")
+	},
+}
+
+var networkShow = &cobra.Command{
+	Use:   "show <networkID|description>",
+	Short: "show a private network and the instances attached to it",
+	Long:  ``,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 {
+			return errors.New("please provide a networkID or description")
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		members, _ := cmd.Flags().GetBool("members")
+
+		ctx := context.Background()
+		network, err := resolveNetwork(ctx, args[0])
+		if err != nil {
+			fmt.Printf("error getting network : %v\n", err)
+			os.Exit(1)
+		}
+
+		printer.Network(network)
+		if !members {
+			return
+		}
+
+		all, err := networkMembers(ctx)
+		if err != nil {
+			fmt.Printf("error getting network members : %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Println()
+		printer.NetworkMembers(all[network.NetworkID])
+	},
+}
+
+var networkTopology = &cobra.Command{
+	Use:   "topology",
+	Short: "show all private networks and their members as a tree or Graphviz DOT",
+	Long:  ``,
+	Run: func(cmd *cobra.Command, args []string) {
+		format, _ := cmd.Flags().GetString("format")
+		if format != "tree" && format != "dot" {
+			fmt.Printf("error rendering topology : unknown format %q\n", format)
+			os.Exit(1)
+		}
+
+		ctx := context.Background()
+		networks, err := listAllNetworks(ctx)
+		if err != nil {
+			fmt.Printf("error getting network list : %v\n", err)
+			os.Exit(1)
+		}
+
+		members, err := networkMembers(ctx)
+		if err != nil {
+			fmt.Printf("error getting network members : %v\n", err)
+			os.Exit(1)
+		}
+
+		sort.SliceStable(networks, func(i, j int) bool {
+			if networks[i].Region == networks[j].Region {
+				return networks[i].Description < networks[j].Description
+			}
+			return networks[i].Region < networks[j].Region
+		})
+
+		if format == "dot" {
+			fmt.Print(topologyDOT(networks, members))
+			return
+		}
+		fmt.Print(topologyTree(networks, members))
+	},
+}
+
+// networkMembers maps each network ID to the instances attached to it
+func networkMembers(ctx context.Context) (map[string][]printer.NetworkMember, error) {
+	instances, err := listAllInstances(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	members := map[string][]printer.NetworkMember{}
+	for _, i := range instances {
+		networks, err := listAllInstanceNetworks(ctx, i.ID)
+		if err != nil {
+			return nil, fmt.Errorf("instance %s : %v", i.ID, err)
+		}
+
+		h := instanceHost(&i)
+		for _, n := range networks {
+			members[n.NetworkID] = append(members[n.NetworkID], printer.NetworkMember{
+				InstanceID: i.ID,
+				Label:      h.Name(),
+				MacAddress: n.MacAddress,
+				IPAddress:  n.IPAddress,
+			})
+		}
+	}
+
+	for id := range members {
+		sort.SliceStable(members[id], func(a, b int) bool {
+			return members[id][a].Label < members[id][b].Label
+		})
+	}
+	return members, nil
+}
+
+func networkName(n *govultr.Network) string {
+	if n.Description != "" {
+		return n.Description
+	}
+	return n.NetworkID
+}
+
+func topologyTree(networks []govultr.Network, members map[string][]printer.NetworkMember) string {
+	var b strings.Builder
+	for _, n := range networks {
+		fmt.Fprintf(&b, "%s (%s/%d, %s, %s)\n", networkName(&n), n.V4Subnet, n.V4SubnetMask, n.Region, n.NetworkID)
+
+		list := members[n.NetworkID]
+		if len(list) == 0 {
+			b.WriteString("└── (no instances)\n")
+		}
+		for i, m := range list {
+			branch := "├──"
+			if i == len(list)-1 {
+				branch = "└──"
+			}
+			fmt.Fprintf(&b, "%s %s  %s  %s  %s\n", branch, m.Label, m.IPAddress, m.MacAddress, m.InstanceID)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func topologyDOT(networks []govultr.Network, members map[string][]printer.NetworkMember) string {
+	var b strings.Builder
+	b.WriteString("graph vultr {\n")
+	b.WriteString("  node [fontname=\"Helvetica\"];\n")
+
+	instances := map[string]string{}
+	for _, n := range networks {
+		fmt.Fprintf(&b, "  %q [shape=box, label=%q];\n", n.NetworkID, fmt.Sprintf("%s\n%s/%d\n%s", networkName(&n), n.V4Subnet, n.V4SubnetMask, n.Region))
+		for _, m := range members[n.NetworkID] {
+			instances[m.InstanceID] = m.Label
+		}
+	}
+
+	ids := make([]string, 0, len(instances))
+	for id := range instances {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		fmt.Fprintf(&b, "  %q [shape=ellipse, label=%q];\n", id, instances[id])
+	}
+
+	for _, n := range networks {
+		for _, m := range members[n.NetworkID] {
+			fmt.Fprintf(&b, "  %q -- %q [label=%q];\n", n.NetworkID, m.InstanceID, m.IPAddress)
+		}
+	}
+
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// listAllNetworks walks every page of the private network list
+func listAllNetworks(ctx context.Context) ([]govultr.Network, error) {
+	var all []govultr.Network
+	options := &govultr.ListOptions{PerPage: 100}
+	for {
+		networks, meta, err := client.Network.List(ctx, options)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, networks...)
+
+		if meta == nil || meta.Links == nil || meta.Links.Next == "" {
+			return all, nil
+		}
+		options.Cursor = meta.Links.Next
+	}
+}
+
+// resolveNetwork looks a private network up by ID or description
+func resolveNetwork(ctx context.Context, ref string) (*govultr.Network, error) {
+	if regUUID.MatchString(ref) {
+		return client.Network.Get(ctx, ref)
+	}
+
+	networks, err := listAllNetworks(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []govultr.Network
+	for _, n := range networks {
+		if n.Description == ref {
+			matches = append(matches, n)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no private network found with the description %q", ref)
+	case 1:
+		return &matches[0], nil
+	default:
+		return nil, fmt.Errorf("%d private networks match %q, please use a networkID", len(matches), ref)
+	}
+}
@@ -21,3 +21,22 @@ func Backup(bs *govultr.Backup) {
 
 	flush()
 }
+
+// RestoreCandidate is a backup or snapshot an instance can be restored from
+type RestoreCandidate struct {
+	Source      string
+	ID          string
+	DateCreated string
+	Size        int64
+	Description string
+	Match       string
+}
+
+func RestoreCandidates(candidates []RestoreCandidate) {
+	col := columns{"#", "TYPE", "ID", "DATE CREATED", "SIZE", "MATCHED BY", "DESCRIPTION"}
+	display(col)
+	for i, c := range candidates {
+		display(columns{i + 1, c.Source, c.ID, c.DateCreated, HumanBytes(c.Size), c.Match, c.Description})
+	}
+	flush()
+}
@@ -36,3 +36,37 @@ func SoaInfo(soa *govultr.Soa) {
 	display(columns{soa.NSPrimary, soa.Email})
 	flush()
 }
+
+// DSRecord is a delegation signer record for the parent zone
+type DSRecord struct {
+	KeyTag     int
+	Algorithm  int
+	DigestType int
+	Digest     string
+}
+
+func DSRecords(records []DSRecord) {
+	col := columns{"KEY TAG", "ALGORITHM", "DIGEST TYPE", "DIGEST"}
+	display(col)
+	for _, r := range records {
+		display(columns{r.KeyTag, r.Algorithm, r.DigestType, r.Digest})
+	}
+	flush()
+}
+
+// DNSSECResult is the outcome of checking a DS record or a published key
+type DNSSECResult struct {
+	Source string
+	KeyTag int
+	Status string
+	Detail string
+}
+
+func DNSSECCheck(results []DNSSECResult) {
+	col := columns{"SOURCE", "KEY TAG", "STATUS", "DETAIL"}
+	display(col)
+	for _, r := range results {
+		display(columns{r.Source, r.KeyTag, r.Status, r.Detail})
+	}
+	flush()
+}
@@ -20,3 +20,77 @@ func DnsRecord(record *govultr.DomainRecord) {
 	display(columns{record.ID, record.Type, record.Name, record.Data, record.Priority, record.TTL})
 	flush()
 }
+
+// DNSRecordChange is a record that a bulk command created, deleted or left
+type DNSRecordChange struct {
+	Action   string
+	Type     string
+	Name     string
+	Data     string
+	Priority int
+	TTL      int
+}
+
+func DNSRecordChanges(changes []DNSRecordChange) {
+	col := columns{"ACTION", "TYPE", "NAME", "DATA", "PRIORITY", "TTL"}
+	display(col)
+	for _, c := range changes {
+		display(columns{c.Action, c.Type, c.Name, c.Data, c.Priority, c.TTL})
+	}
+	flush()
+}
+
+// ZoneSkipped is an entry of a zone file that could not be imported
+type ZoneSkipped struct {
+	Line   int
+	Text   string
+	Reason string
+}
+
+func ZoneSkippedList(skipped []ZoneSkipped) {
+	col := columns{"LINE", "REASON", "ENTRY"}
+	display(col)
+	for _, s := range skipped {
+		display(columns{s.Line, s.Reason, s.Text})
+	}
+	flush()
+}
+
+// DNSRecordMatch is a record found by a search across domains
+type DNSRecordMatch struct {
+	Domain string
+	ID     string
+	Type   string
+	Name   string
+	Data   string
+	TTL    int
+}
+
+func DNSRecordMatches(matches []DNSRecordMatch) {
+	col := columns{"DOMAIN", "ID", "TYPE", "NAME", "DATA", "TTL"}
+	display(col)
+	for _, m := range matches {
+		display(columns{m.Domain, m.ID, m.Type, m.Name, m.Data, m.TTL})
+	}
+	flush()
+}
+
+// DNSAuditResult is the outcome of checking one record in dns audit
+type DNSAuditResult struct {
+	Domain string
+	ID     string
+	Type   string
+	Name   string
+	Data   string
+	Status string
+	Detail string
+}
+
+func DNSAudit(results []DNSAuditResult) {
+	col := columns{"STATUS", "TYPE", "NAME", "DATA", "DETAIL", "ID"}
+	display(col)
+	for _, r := range results {
+		display(columns{r.Status, r.Type, r.Name, r.Data, r.Detail, r.ID})
+	}
+	flush()
+}
@@ -1,6 +1,10 @@
 package printer
 
-import "github.com/vultr/govultr/v2"
+import (
+	"strings"
+
+	"github.com/vultr/govultr/v2"
+)
 
 func InstanceBandwidth(bandwidth *govultr.Bandwidth) {
 	col := columns{"DATE", "INCOMING BYTES", "OUTGOING BYTES"}
@@ -95,10 +99,36 @@ func AppList(app []govultr.Application) {
 	flush()
 }
 
-func BackupsGet(b *govultr.BackupSchedule) {
-	col := columns{"ENABLED", "CRON TYPE", "NEXT RUN", "HOUR", "DOW", "DOM"}
+func BackupsGet(b *govultr.BackupSchedule, schedule, nextRun string) {
+	enabled := false
+	if b.Enabled != nil {
+		enabled = *b.Enabled
+	}
+
+	display(columns{"ENABLED", enabled})
+	display(columns{"SCHEDULE", schedule})
+	display(columns{"NEXT RUN", nextRun})
+	display(columns{"CRON TYPE", b.Type})
+	display(columns{"HOUR", b.Hour})
+	display(columns{"DOW", b.Dow})
+	display(columns{"DOM", b.Dom})
+	flush()
+}
+
+// BackupPolicyResult is what backup policy apply did to one instance
+type BackupPolicyResult struct {
+	InstanceID string
+	Label      string
+	Schedule   string
+	Action     string
+}
+
+func BackupPolicyResults(results []BackupPolicyResult) {
+	col := columns{"INSTANCE ID", "LABEL", "SCHEDULE", "ACTION"}
 	display(col)
-	display(columns{*b.Enabled, b.Type, b.NextScheduleTimeUTC, b.Hour, b.Dow, b.Dom})
+	for _, r := range results {
+		display(columns{r.InstanceID, r.Label, r.Schedule, r.Action})
+	}
 	flush()
 }
 
@@ -126,3 +156,88 @@ func ReverseIpv6(rip []govultr.ReverseIP) {
 	}
 	flush()
 }
+
+func InstancePrivateNetworks(networks []govultr.PrivateNetwork, meta *govultr.Meta) {
+	col := columns{"NETWORK ID", "MAC ADDRESS", "IP ADDRESS"}
+	display(col)
+	for _, n := range networks {
+		display(columns{n.NetworkID, n.MacAddress, n.IPAddress})
+	}
+
+	Meta(meta)
+	flush()
+}
+
+func InstanceNeighbors(neighbors *govultr.Neighbors) {
+	col := columns{"NEIGHBOR ID"}
+	display(col)
+	for _, n := range neighbors.Neighbors {
+		display(columns{n})
+	}
+	flush()
+}
+
+// AffinityMember is one instance of a group that shares a physical host
+type AffinityMember struct {
+	Group    int
+	ID       string
+	Label    string
+	Tag      string
+	Region   string
+	Conflict string
+}
+
+func AffinityReport(members []AffinityMember) {
+	col := columns{"HOST GROUP", "ID", "LABEL", "TAG", "REGION", "CONFLICT"}
+	display(col)
+	for _, m := range members {
+		display(columns{m.Group, m.ID, m.Label, m.Tag, m.Region, m.Conflict})
+	}
+	flush()
+}
+
+func InstancesCreated(instances []govultr.Instance) {
+	col := columns{"ID", "LABEL", "HOSTNAME", "REGION", "PLAN", "OS", "STATUS", "DEFAULT PASSWORD"}
+	display(col)
+	for _, s := range instances {
+		display(columns{s.ID, s.Label, s.Hostname, s.Region, s.Plan, s.Os, s.Status, s.DefaultPassword})
+	}
+	flush()
+}
+
+// ReverseDNSChange is a reverse DNS entry that sync set or would set
+type ReverseDNSChange struct {
+	InstanceID string
+	IP         string
+	Current    string
+	Desired    string
+	Action     string
+}
+
+func ReverseDNSChanges(changes []ReverseDNSChange) {
+	col := columns{"INSTANCE ID", "IP", "CURRENT", "DESIRED", "ACTION"}
+	display(col)
+	for _, c := range changes {
+		display(columns{c.InstanceID, c.IP, c.Current, c.Desired, c.Action})
+	}
+	flush()
+}
+
+// ReverseDNSResult is the outcome of checking one reverse DNS entry
+type ReverseDNSResult struct {
+	InstanceID string
+	Name       string
+	IP         string
+	PTR        string
+	Status     string
+	Forward    []string
+}
+
+func ReverseDNSCheck(results []ReverseDNSResult) {
+	col := columns{"INSTANCE ID", "NAME", "IP", "PTR", "STATUS", "FORWARD"}
+	display(col)
+	for _, r := range results {
+		display(columns{r.InstanceID, r.Name, r.IP, r.PTR, r.Status, strings.Join(r.Forward, ",")})
+	}
+	flush()
+}
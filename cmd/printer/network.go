@@ -19,3 +19,21 @@ func Network(network *govultr.Network) {
 
 	flush()
 }
+
+// NetworkMember is an instance attached to a private network
+type NetworkMember struct {
+	InstanceID string
+	Label      string
+	MacAddress string
+	IPAddress  string
+}
+
+func NetworkMembers(members []NetworkMember) {
+	col := columns{"INSTANCE ID", "LABEL", "MAC ADDRESS", "IP ADDRESS"}
+	display(col)
+	for _, m := range members {
+		display(columns{m.InstanceID, m.Label, m.MacAddress, m.IPAddress})
+	}
+
+	flush()
+}
@@ -1,6 +1,8 @@
 package printer
 
 import (
+	"fmt"
+
 	"github.com/vultr/govultr/v2"
 )
 
@@ -25,3 +27,30 @@ func PlanBareMetal(plan []govultr.BareMetalPlan, meta *govultr.Meta) {
 	Meta(meta)
 	flush()
 }
+
+// PlanOption is a plan an instance can move to and what it costs compared
+// to the current one
+type PlanOption struct {
+	Plan      govultr.Plan
+	CostDelta float32
+}
+
+func PlanAdvice(current *govultr.Plan, options []PlanOption) {
+	if current != nil {
+		display(columns{"CURRENT PLAN", current.ID})
+		display(columns{"VCPU COUNT", current.VCPUCount})
+		display(columns{"RAM", current.RAM})
+		display(columns{"DISK", current.Disk})
+		display(columns{"BANDWIDTH GB", current.Bandwidth})
+		display(columns{"PRICE PER MONTH", fmt.Sprintf("%.2f", current.MonthlyCost)})
+		display(columns{"---------------------------"})
+	}
+
+	col := columns{"ID", "VCPU COUNT", "RAM", "DISK", "BANDWIDTH GB", "PRICE PER MONTH", "COST DELTA", "TYPE"}
+	display(col)
+	for _, o := range options {
+		p := o.Plan
+		display(columns{p.ID, p.VCPUCount, p.RAM, p.Disk, p.Bandwidth, fmt.Sprintf("%.2f", p.MonthlyCost), fmt.Sprintf("%+.2f", o.CostDelta), p.Type})
+	}
+	flush()
+}
@@ -0,0 +1,63 @@
+package printer
+
+import (
+	"fmt"
+)
+
+// BandwidthUsage is the month to date bandwidth of one server
+type BandwidthUsage struct {
+	ID                 string  `json:"id"`
+	Type               string  `json:"type"`
+	Name               string  `json:"name"`
+	Region             string  `json:"region"`
+	Plan               string  `json:"plan"`
+	IncomingBytes      int64   `json:"incoming_bytes"`
+	OutgoingBytes      int64   `json:"outgoing_bytes"`
+	AllowedBytes       int64   `json:"allowed_bytes"`
+	ProjectedBytes     int64   `json:"projected_bytes"`
+	ProjectedOverBytes int64   `json:"projected_overage_bytes"`
+	ProjectedCost      float64 `json:"projected_overage_cost"`
+}
+
+func BandwidthReport(usage []BandwidthUsage) {
+	col := columns{"ID", "TYPE", "NAME", "REGION", "INCOMING", "OUTGOING", "ALLOWED", "USED", "PROJECTED", "PROJECTED OVERAGE", "OVERAGE COST"}
+	display(col)
+
+	var total BandwidthUsage
+	for _, u := range usage {
+		display(columns{u.ID, u.Type, u.Name, u.Region, HumanBytes(u.IncomingBytes), HumanBytes(u.OutgoingBytes), HumanBytes(u.AllowedBytes), usedPercent(u), HumanBytes(u.ProjectedBytes), HumanBytes(u.ProjectedOverBytes), fmt.Sprintf("%.2f", u.ProjectedCost)})
+
+		total.IncomingBytes += u.IncomingBytes
+		total.OutgoingBytes += u.OutgoingBytes
+		total.AllowedBytes += u.AllowedBytes
+		total.ProjectedBytes += u.ProjectedBytes
+		total.ProjectedOverBytes += u.ProjectedOverBytes
+		total.ProjectedCost += u.ProjectedCost
+	}
+
+	display(columns{"TOTAL", "", "", "", HumanBytes(total.IncomingBytes), HumanBytes(total.OutgoingBytes), HumanBytes(total.AllowedBytes), usedPercent(total), HumanBytes(total.ProjectedBytes), HumanBytes(total.ProjectedOverBytes), fmt.Sprintf("%.2f", total.ProjectedCost)})
+	flush()
+}
+
+func usedPercent(u BandwidthUsage) string {
+	if u.AllowedBytes == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%%", float64(u.OutgoingBytes)*100/float64(u.AllowedBytes))
+}
+
+// HumanBytes formats a byte count with decimal units, which is how Vultr
+// sizes bandwidth allowances.
+func HumanBytes(b int64) string {
+	const unit = 1000
+	if b < unit {
+		return fmt.Sprintf("%d B", b)
+	}
+
+	div, exp := int64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.2f %cB", float64(b)/float64(div), "kMGTPE"[exp])
+}
@@ -8,13 +8,12 @@ import (
 	"github.com/vultr/govultr/v2"
 )
 
+// UserData writes the decoded user-data as is, so it can be redirected to a file
 func UserData(u *govultr.UserData) {
-	display(columns{"USERDATA"})
 	data, err := base64.StdEncoding.DecodeString(u.Data)
 	if err != nil {
 		fmt.Printf("Error decoding user-data: %v\n", err)
 		os.Exit(1)
 	}
-	display(columns{string(data)})
-	flush()
+	os.Stdout.Write(data)
 }
@@ -0,0 +1,267 @@
+// Copyright © 2019 The Vultr-cli Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/vultr/govultr/v2"
+	"github.com/vultr/vultr-cli/v2/cmd/printer"
+)
+
+const (
+	reportFormatHuman = "human"
+	reportFormatCSV   = "csv"
+	reportFormatJSON  = "json"
+
+	// bytesPerGB is the unit Vultr uses for bandwidth allowances
+	bytesPerGB = 1000 * 1000 * 1000
+)
+
+// Report represents the report command
+func Report() *cobra.Command {
+	reportCmd := &cobra.Command{
+		Use:   "report",
+		Short: "reports across all servers on the account",
+		Long:  ``,
+	}
+
+	reportCmd.AddCommand(reportBandwidth)
+	reportBandwidth.Flags().StringP("format", "f", reportFormatHuman, "output format : Possible values human, csv, json")
+	reportBandwidth.Flags().StringP("tag", "t", "", "(optional) only include servers with this tag. A trailing * matches by prefix")
+	reportBandwidth.Flags().IntP("top", "n", 0, "(optional) only show the n largest consumers")
+	reportBandwidth.Flags().Float64("overage-price", 0.01, "(optional) price per GB over the allowance used for the projected cost")
+	reportBandwidth.Flags().Int("parallel", 5, "(optional) number of servers to query at once")
+
+	return reportCmd
+}
+
+var reportBandwidth = &cobra.Command{
+	Use:   "bandwidth",
+	Short: "month to date bandwidth of every instance and bare metal server",
+	Long: `bandwidth adds up this month's traffic of every instance and bare metal server
+and sorts them by outgoing bytes, which is what counts towards the allowance of
+the plan.
+
+The end of month figure is projected linearly from the days that have passed,
+and anything above the allowance is priced with --overage-price.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		format, _ := cmd.Flags().GetString("format")
+		tag, _ := cmd.Flags().GetString("tag")
+		top, _ := cmd.Flags().GetInt("top")
+		price, _ := cmd.Flags().GetFloat64("overage-price")
+		parallel, _ := cmd.Flags().GetInt("parallel")
+
+		switch format {
+		case reportFormatHuman, reportFormatCSV, reportFormatJSON:
+		default:
+			fmt.Printf("error generating report : unknown format %q\n", format)
+			os.Exit(1)
+		}
+
+		usage, err := bandwidthUsage(context.Background(), tag, parallel, price, time.Now().UTC())
+		if err != nil {
+			fmt.Printf("error generating report : %v\n", err)
+			os.Exit(1)
+		}
+
+		if top > 0 && len(usage) > top {
+			usage = usage[:top]
+		}
+
+		switch format {
+		case reportFormatCSV:
+			err = writeBandwidthCSV(usage)
+		case reportFormatJSON:
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			err = enc.Encode(usage)
+		default:
+			printer.BandwidthReport(usage)
+		}
+
+		if err != nil {
+			fmt.Printf("error writing report : %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// bandwidthUsage collects and projects the bandwidth of every server that
+// matches the tag, largest consumers first.
+func bandwidthUsage(ctx context.Context, tag string, parallel int, price float64, now time.Time) ([]printer.BandwidthUsage, error) {
+	instances, err := listAllInstances(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	servers, err := listAllBareMetal(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Bare metal servers do not report their allowance, their plan does
+	var plans []govultr.BareMetalPlan
+	if len(servers) > 0 {
+		if plans, err = listAllBareMetalPlans(ctx); err != nil {
+			return nil, err
+		}
+	}
+	allowances := make(map[string]int, len(plans))
+	for _, p := range plans {
+		allowances[p.ID] = p.Bandwidth
+	}
+
+	var usage []printer.BandwidthUsage
+	for i := range instances {
+		h := instanceHost(&instances[i])
+		if tag != "" && !matchTag(h.Tag, tag) {
+			continue
+		}
+		usage = append(usage, bandwidthRow(h, instances[i].AllowedBandwidth))
+	}
+	for i := range servers {
+		h := bareMetalHost(&servers[i])
+		if tag != "" && !matchTag(h.Tag, tag) {
+			continue
+		}
+		usage = append(usage, bandwidthRow(h, allowances[h.Plan]))
+	}
+
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	errs := make([]error, len(usage))
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for i := range usage {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(u *printer.BandwidthUsage, err *error) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			var bw *govultr.Bandwidth
+			if u.Type == hostTypeBareMetal {
+				bw, *err = client.BareMetalServer.GetBandwidth(ctx, u.ID)
+			} else {
+				bw, *err = client.Instance.GetBandwidth(ctx, u.ID)
+			}
+			if *err != nil {
+				*err = fmt.Errorf("%s : %v", u.Name, *err)
+				return
+			}
+
+			projectBandwidth(u, bw, price, now)
+		}(&usage[i], &errs[i])
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sort.SliceStable(usage, func(i, j int) bool {
+		return usage[i].OutgoingBytes > usage[j].OutgoingBytes
+	})
+
+	return usage, nil
+}
+
+func bandwidthRow(h fleetHost, allowedGB int) printer.BandwidthUsage {
+	return printer.BandwidthUsage{
+		ID:           h.ID,
+		Type:         h.Type,
+		Name:         h.Name(),
+		Region:       h.Region,
+		Plan:         h.Plan,
+		AllowedBytes: int64(allowedGB) * bytesPerGB,
+	}
+}
+
+// projectBandwidth sums this month's days of bw into u and extrapolates the
+// outgoing traffic to the end of the month.
+func projectBandwidth(u *printer.BandwidthUsage, bw *govultr.Bandwidth, price float64, now time.Time) {
+	month := now.Format("2006-01")
+	for day, b := range bw.Bandwidth {
+		if !strings.HasPrefix(day, month) {
+			continue
+		}
+		u.IncomingBytes += int64(b.IncomingBytes)
+		u.OutgoingBytes += int64(b.OutgoingBytes)
+	}
+
+	daysInMonth := time.Date(now.Year(), now.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	u.ProjectedBytes = u.OutgoingBytes * int64(daysInMonth) / int64(now.Day())
+
+	if u.AllowedBytes > 0 && u.ProjectedBytes > u.AllowedBytes {
+		u.ProjectedOverBytes = u.ProjectedBytes - u.AllowedBytes
+		u.ProjectedCost = math.Round(float64(u.ProjectedOverBytes)/bytesPerGB*price*100) / 100
+	}
+}
+
+// listAllBareMetalPlans walks every page of the bare metal plan list
+func listAllBareMetalPlans(ctx context.Context) ([]govultr.BareMetalPlan, error) {
+	var all []govultr.BareMetalPlan
+	options := &govultr.ListOptions{PerPage: 100}
+	for {
+		plans, meta, err := client.Plan.ListBareMetal(ctx, options)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, plans...)
+
+		if meta == nil || meta.Links == nil || meta.Links.Next == "" {
+			return all, nil
+		}
+		options.Cursor = meta.Links.Next
+	}
+}
+
+func writeBandwidthCSV(usage []printer.BandwidthUsage) error {
+	w := csv.NewWriter(os.Stdout)
+	w.Write([]string{"id", "type", "name", "region", "plan", "incoming_bytes", "outgoing_bytes", "allowed_bytes", "projected_bytes", "projected_overage_bytes", "projected_overage_cost"})
+	for _, u := range usage {
+		w.Write([]string{
+			u.ID,
+			u.Type,
+			u.Name,
+			u.Region,
+			u.Plan,
+			strconv.FormatInt(u.IncomingBytes, 10),
+			strconv.FormatInt(u.OutgoingBytes, 10),
+			strconv.FormatInt(u.AllowedBytes, 10),
+			strconv.FormatInt(u.ProjectedBytes, 10),
+			strconv.FormatInt(u.ProjectedOverBytes, 10),
+			strconv.FormatFloat(u.ProjectedCost, 'f', 2, 64),
+		})
+	}
+	w.Flush()
+	return w.Error()
+}
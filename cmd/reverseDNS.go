@@ -0,0 +1,358 @@
+// Copyright © 2019 The Vultr-cli Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/vultr/govultr/v2"
+	"github.com/vultr/vultr-cli/v2/cmd/printer"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	reverseDNSOK        = "ok"
+	reverseDNSMissing   = "missing"
+	reverseDNSUnmanaged = "unmanaged"
+	reverseDNSNoForward = "no-forward"
+	reverseDNSMismatch  = "mismatch"
+	reverseDNSUnchanged = "unchanged"
+	reverseDNSUpdate    = "update"
+)
+
+var reverseDNSSync = &cobra.Command{
+	Use:   "sync",
+	Short: "set reverse DNS entries for the IPs of many instances",
+	Long: `sync sets the reverse DNS entry of every IPv4 address and the main IPv6 address
+of the selected instances.
+
+Entries come from --file, a YAML map of IP address to name, and for the IPs
+that are not in it from --template, a Go template rendered with .ID, .Label,
+.Hostname, .Tag, .Region, .Plan, .MainIP, .V6MainIP and .IP.`,
+	Example: `
+	# Use the hostname of every instance tagged mail
+	vultr-cli instance reverse-dns sync --tag mail --template '{{.Hostname}}.example.com'
+
+	# Set entries from a file and see what would change first
+	vultr-cli instance reverse-dns sync --file ptr.yaml --dry-run
+	`,
+	Args: reverseDNSArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		tmpl, _ := cmd.Flags().GetString("template")
+		file, _ := cmd.Flags().GetString("file")
+		tag, _ := cmd.Flags().GetString("tag")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		if tmpl == "" && file == "" {
+			fmt.Println("error syncing reverse dns : please provide a --template or a --file")
+			os.Exit(1)
+		}
+
+		mapping := map[string]string{}
+		if file != "" {
+			data, err := ioutil.ReadFile(file)
+			if err != nil {
+				fmt.Printf("error syncing reverse dns : %v\n", err)
+				os.Exit(1)
+			}
+			if err := yaml.UnmarshalStrict(data, &mapping); err != nil {
+				fmt.Printf("error parsing %s : %v\n", file, err)
+				os.Exit(1)
+			}
+		}
+
+		ctx := context.Background()
+		instances, err := selectInstances(ctx, tag)
+		if err != nil {
+			fmt.Printf("error listing instances : %v\n", err)
+			os.Exit(1)
+		}
+
+		var changes []printer.ReverseDNSChange
+		failed := false
+		for i := range instances {
+			instance := &instances[i]
+			entries, err := instancePTRs(ctx, instance)
+			if err != nil {
+				fmt.Printf("error getting reverse dns for %s : %v\n", instance.ID, err)
+				os.Exit(1)
+			}
+
+			for _, e := range entries {
+				desired, ok := mapping[e.IP]
+				if !ok && tmpl != "" {
+					data := &instanceTemplateData{
+						ID:         instance.ID,
+						Label:      instance.Label,
+						Hostname:   instance.Hostname,
+						Tag:        instance.Tag,
+						Region:     instance.Region,
+						Plan:       instance.Plan,
+						MainIP:     instance.MainIP,
+						V6MainIP:   instance.V6MainIP,
+						InternalIP: instance.InternalIP,
+						IP:         e.IP,
+					}
+					if desired, err = renderNameTemplate("template", tmpl, data); err != nil {
+						fmt.Printf("error syncing reverse dns : %v\n", err)
+						os.Exit(1)
+					}
+				}
+				if desired == "" {
+					continue
+				}
+
+				change := printer.ReverseDNSChange{InstanceID: instance.ID, IP: e.IP, Current: e.Current, Desired: desired, Action: reverseDNSUpdate}
+
+				// A template can render a broken name, such as .example.com
+				// for an instance without a hostname
+				if _, err := normalizeHostname(desired); err != nil {
+					change.Action = fmt.Sprintf("error : %v", err)
+					failed = true
+					changes = append(changes, change)
+					continue
+				}
+
+				if canonicalName(e.Current) == canonicalName(desired) {
+					change.Action = reverseDNSUnchanged
+				} else if !dryRun {
+					if err := setReverseDNS(ctx, instance.ID, e.IP, desired); err != nil {
+						change.Action = fmt.Sprintf("error : %v", err)
+						failed = true
+					}
+				}
+				changes = append(changes, change)
+			}
+		}
+
+		printer.ReverseDNSChanges(changes)
+		if failed {
+			os.Exit(1)
+		}
+	},
+}
+
+var reverseDNSCheck = &cobra.Command{
+	Use:   "check",
+	Short: "compare reverse DNS entries with A and AAAA records in Vultr DNS",
+	Long: `check looks up the reverse DNS name of every instance IP in the domains hosted
+on Vultr DNS and reports the IPs whose name does not resolve back to them.
+
+Statuses are missing (no reverse entry), unmanaged (the name is not in a Vultr
+DNS domain, so it can not be checked), no-forward (the domain has no A or AAAA
+record for the name) and mismatch (the record points somewhere else).`,
+	Args: reverseDNSArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		tag, _ := cmd.Flags().GetString("tag")
+		all, _ := cmd.Flags().GetBool("all")
+		failOnMismatch, _ := cmd.Flags().GetBool("fail-on-mismatch")
+
+		ctx := context.Background()
+		instances, err := selectInstances(ctx, tag)
+		if err != nil {
+			fmt.Printf("error listing instances : %v\n", err)
+			os.Exit(1)
+		}
+
+		zones, forward, err := forwardRecords(ctx)
+		if err != nil {
+			fmt.Printf("error listing dns records : %v\n", err)
+			os.Exit(1)
+		}
+
+		var results []printer.ReverseDNSResult
+		mismatch := false
+		for i := range instances {
+			entries, err := instancePTRs(ctx, &instances[i])
+			if err != nil {
+				fmt.Printf("error getting reverse dns for %s : %v\n", instances[i].ID, err)
+				os.Exit(1)
+			}
+
+			for _, e := range entries {
+				r := checkPTR(e, zones, forward)
+				if r.Status != reverseDNSOK && r.Status != reverseDNSUnmanaged {
+					mismatch = true
+				}
+				if all || r.Status != reverseDNSOK {
+					results = append(results, r)
+				}
+			}
+		}
+
+		printer.ReverseDNSCheck(results)
+		if failOnMismatch && mismatch {
+			os.Exit(1)
+		}
+	},
+}
+
+// ptrEntry is the reverse DNS entry of one instance IP
+type ptrEntry struct {
+	InstanceID string
+	Name       string
+	IP         string
+	Current    string
+}
+
+// selectInstances lists the instances, keeping those that match the tag
+// selector when one is given
+func selectInstances(ctx context.Context, tag string) ([]govultr.Instance, error) {
+	instances, err := listAllInstances(ctx)
+	if err != nil || tag == "" {
+		return instances, err
+	}
+
+	var selected []govultr.Instance
+	for _, i := range instances {
+		if matchTag(i.Tag, tag) {
+			selected = append(selected, i)
+		}
+	}
+	return selected, nil
+}
+
+// instancePTRs returns the reverse DNS entries of every IPv4 address and of
+// the main IPv6 address of an instance
+func instancePTRs(ctx context.Context, instance *govultr.Instance) ([]ptrEntry, error) {
+	host := instanceHost(instance)
+	name := host.Name()
+
+	var entries []ptrEntry
+	options := &govultr.ListOptions{PerPage: 100}
+	for {
+		ips, meta, err := client.Instance.ListIPv4(ctx, instance.ID, options)
+		if err != nil {
+			return nil, err
+		}
+		for _, ip := range ips {
+			entries = append(entries, ptrEntry{InstanceID: instance.ID, Name: name, IP: ip.IP, Current: ip.Reverse})
+		}
+
+		if meta == nil || meta.Links == nil || meta.Links.Next == "" {
+			break
+		}
+		options.Cursor = meta.Links.Next
+	}
+
+	if instance.V6MainIP == "" {
+		return entries, nil
+	}
+
+	reverse, err := client.Instance.ListReverseIPv6(ctx, instance.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	v6 := ptrEntry{InstanceID: instance.ID, Name: name, IP: instance.V6MainIP}
+	for _, r := range reverse {
+		if sameIP(r.IP, instance.V6MainIP) {
+			v6.Current = r.Reverse
+		}
+	}
+	return append(entries, v6), nil
+}
+
+func setReverseDNS(ctx context.Context, instanceID, ip, name string) error {
+	options := &govultr.ReverseIP{IP: ip, Reverse: name}
+	if net.ParseIP(ip).To4() != nil {
+		return client.Instance.CreateReverseIPv4(ctx, instanceID, options)
+	}
+	return client.Instance.CreateReverseIPv6(ctx, instanceID, options)
+}
+
+// forwardRecords returns the domains on the account and the addresses of
+// every A and AAAA record in them by full name
+func forwardRecords(ctx context.Context) ([]string, map[string][]string, error) {
+	domains, err := listAllDomains(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	zones := make([]string, 0, len(domains))
+	forward := map[string][]string{}
+	for _, d := range domains {
+		zones = append(zones, d.Domain)
+
+		records, err := listAllDomainRecords(ctx, d.Domain)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s : %v", d.Domain, err)
+		}
+		for _, r := range records {
+			if r.Type == "A" || r.Type == "AAAA" {
+				name := recordFQDN(d.Domain, r.Name)
+				forward[name] = append(forward[name], r.Data)
+			}
+		}
+	}
+
+	return zones, forward, nil
+}
+
+// checkPTR resolves the reverse DNS name of an IP against the forward records
+func checkPTR(e ptrEntry, zones []string, forward map[string][]string) printer.ReverseDNSResult {
+	r := printer.ReverseDNSResult{InstanceID: e.InstanceID, Name: e.Name, IP: e.IP, PTR: e.Current}
+
+	if e.Current == "" {
+		r.Status = reverseDNSMissing
+		return r
+	}
+
+	name := canonicalName(e.Current)
+	if zoneForName(name, zones) == "" {
+		r.Status = reverseDNSUnmanaged
+		return r
+	}
+
+	addresses := forward[name]
+	if len(addresses) == 0 {
+		r.Status = reverseDNSNoForward
+		return r
+	}
+
+	for _, a := range addresses {
+		if sameIP(a, e.IP) {
+			r.Status = reverseDNSOK
+			return r
+		}
+	}
+
+	r.Status = reverseDNSMismatch
+	r.Forward = addresses
+	return r
+}
+
+// sameIP compares two addresses regardless of how IPv6 is written
+func sameIP(a, b string) bool {
+	ipA, ipB := net.ParseIP(a), net.ParseIP(b)
+	if ipA == nil || ipB == nil {
+		return a == b
+	}
+	return ipA.Equal(ipB)
+}
+
+// reverseDNSArgs rejects positional arguments on the fleet wide commands
+func reverseDNSArgs(cmd *cobra.Command, args []string) error {
+	if len(args) > 0 {
+		return errors.New("reverse-dns sync and check select instances with --tag")
+	}
+	return nil
+}
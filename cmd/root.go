@@ -62,6 +62,7 @@ func init() {
 	rootCmd.AddCommand(BlockStorageCmd())
 	rootCmd.AddCommand(DNS())
 	rootCmd.AddCommand(Firewall())
+	rootCmd.AddCommand(Inventory())
 	rootCmd.AddCommand(ISO())
 	rootCmd.AddCommand(Kubernetes())
 	rootCmd.AddCommand(LoadBalancer())
@@ -70,10 +71,12 @@ func init() {
 	rootCmd.AddCommand(ObjectStorageCmd())
 	rootCmd.AddCommand(Plans())
 	rootCmd.AddCommand(Regions())
+	rootCmd.AddCommand(Report())
 	rootCmd.AddCommand(ReservedIP())
 	rootCmd.AddCommand(Script())
 	rootCmd.AddCommand(Instance())
 	rootCmd.AddCommand(Snapshot())
+	rootCmd.AddCommand(SSHConfig())
 	rootCmd.AddCommand(SSHKey())
 	rootCmd.AddCommand(User())
 	cobra.OnInitialize(initConfig)
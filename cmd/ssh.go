@@ -0,0 +1,351 @@
+// Copyright © 2019 The Vultr-cli Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+)
+
+// sshFlags registers the flags shared by the instance and bare metal ssh commands
+func sshFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("user", "u", "", "(optional) user to log in as. Defaults to the user of the server's OS family")
+	cmd.Flags().BoolP("ipv6", "6", false, "(optional) connect to the main IPv6 address")
+	cmd.Flags().StringP("command", "c", "", "(optional) run a command instead of opening a shell")
+	cmd.Flags().StringP("tag", "t", "", "(optional) run --command on every server with this tag. A trailing * matches by prefix")
+	cmd.Flags().Int("parallel", 10, "(optional) number of servers to run --command on at once")
+}
+
+var instanceSSH = &cobra.Command{
+	Use:   "ssh <instanceID|label> [-- ssh args]",
+	Short: "ssh into an instance",
+	Long: `ssh resolves the address and login user of an instance and runs ssh.
+
+Anything after -- is passed to ssh. With --tag and --command the command is
+run on every matching instance in parallel and each output line is prefixed
+with the instance name.`,
+	Example: `  vultr-cli instance ssh web-1
+  vultr-cli instance ssh web-1 --private -- -L 8080:localhost:80
+  vultr-cli instance ssh --tag etcd --command "systemctl is-active etcd"`,
+	Args: sshArgs("please provide an instanceID or label"),
+	Run: func(cmd *cobra.Command, args []string) {
+		sshRun(cmd, args, hostTypeInstance)
+	},
+}
+
+var bareMetalSSH = &cobra.Command{
+	Use:   "ssh <bareMetalID|label> [-- ssh args]",
+	Short: "ssh into a bare metal server",
+	Long: `ssh resolves the address and login user of a bare metal server and runs ssh.
+
+Anything after -- is passed to ssh. With --tag and --command the command is
+run on every matching server in parallel and each output line is prefixed
+with the server name.`,
+	Args: sshArgs("please provide a bareMetalID or label"),
+	Run: func(cmd *cobra.Command, args []string) {
+		sshRun(cmd, args, hostTypeBareMetal)
+	},
+}
+
+func sshArgs(missing string) cobra.PositionalArgs {
+	return func(cmd *cobra.Command, args []string) error {
+		tag, _ := cmd.Flags().GetString("tag")
+		command, _ := cmd.Flags().GetString("command")
+
+		// Arguments after -- go to ssh and are not counted
+		positional := len(args)
+		if dash := cmd.ArgsLenAtDash(); dash >= 0 {
+			positional = dash
+		}
+
+		if tag != "" {
+			if command == "" {
+				return errors.New("--tag requires --command")
+			}
+			if positional > 0 {
+				return errors.New("please provide either a server or --tag, not both")
+			}
+			return nil
+		}
+
+		switch {
+		case positional < 1:
+			return errors.New(missing)
+		case positional > 1:
+			return errors.New("please provide only one server. Arguments for ssh go after --")
+		}
+		return nil
+	}
+}
+
+func sshRun(cmd *cobra.Command, args []string, hostType string) {
+	user, _ := cmd.Flags().GetString("user")
+	ipv6, _ := cmd.Flags().GetBool("ipv6")
+	private, _ := cmd.Flags().GetBool("private")
+	command, _ := cmd.Flags().GetString("command")
+	tag, _ := cmd.Flags().GetString("tag")
+	parallel, _ := cmd.Flags().GetInt("parallel")
+
+	// Everything after -- goes to ssh untouched
+	var extra []string
+	if dash := cmd.ArgsLenAtDash(); dash >= 0 {
+		extra = args[dash:]
+		args = args[:dash]
+	}
+
+	ctx := context.Background()
+	families, err := osFamilies(ctx)
+	if err != nil {
+		fmt.Printf("error getting operating systems : %v\n", err)
+		os.Exit(1)
+	}
+
+	var hosts []fleetHost
+	if tag != "" {
+		all, err := listFleet(ctx)
+		if err != nil {
+			fmt.Printf("error getting servers : %v\n", err)
+			os.Exit(1)
+		}
+		for _, h := range filterHosts(all, tag) {
+			if h.Type == hostType {
+				hosts = append(hosts, h)
+			}
+		}
+		if len(hosts) == 0 {
+			fmt.Printf("no servers found with the tag %q\n", tag)
+			os.Exit(1)
+		}
+	} else {
+		var h fleetHost
+		if hostType == hostTypeBareMetal {
+			b, err := resolveBareMetal(ctx, args[0])
+			if err != nil {
+				fmt.Printf("error getting bare metal server : %v\n", err)
+				os.Exit(1)
+			}
+			h = bareMetalHost(b)
+		} else {
+			i, err := resolveInstance(ctx, args[0])
+			if err != nil {
+				fmt.Printf("error getting instance : %v\n", err)
+				os.Exit(1)
+			}
+			h = instanceHost(i)
+		}
+		hosts = append(hosts, h)
+	}
+
+	targets := make([]sshTarget, 0, len(hosts))
+	for i := range hosts {
+		t, err := newSSHTarget(&hosts[i], families, user, private, ipv6)
+		if err != nil {
+			fmt.Printf("error connecting to %s : %v\n", hosts[i].Name(), err)
+			os.Exit(1)
+		}
+		targets = append(targets, t)
+	}
+
+	if tag == "" {
+		code, err := sshInteractive(targets[0], extra, command)
+		if err != nil {
+			fmt.Printf("error running ssh : %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(code)
+	}
+
+	if failed := sshParallel(targets, extra, command, parallel); failed > 0 {
+		fmt.Fprintf(os.Stderr, "command failed on %d of %d servers\n", failed, len(targets))
+		os.Exit(1)
+	}
+}
+
+type sshTarget struct {
+	Name    string
+	User    string
+	Address string
+}
+
+func (t sshTarget) destination() string {
+	return fmt.Sprintf("%s@%s", t.User, t.Address)
+}
+
+func newSSHTarget(h *fleetHost, families map[int]string, user string, private, ipv6 bool) (sshTarget, error) {
+	t := sshTarget{Name: h.Name(), User: user}
+	if t.User == "" {
+		t.User = sshUserForFamily(families[h.OsID])
+	}
+
+	switch {
+	case private:
+		if h.InternalIP == "" {
+			return t, errors.New("no internal IP is assigned")
+		}
+		t.Address = h.InternalIP
+	case ipv6:
+		if h.V6MainIP == "" {
+			return t, errors.New("no IPv6 address is assigned")
+		}
+		t.Address = h.V6MainIP
+	default:
+		if h.MainIP == "" || h.MainIP == "0.0.0.0" {
+			return t, errors.New("no IPv4 address is assigned yet")
+		}
+		t.Address = h.MainIP
+	}
+
+	return t, nil
+}
+
+// osFamilies maps OS IDs to their family, e.g. 387 => ubuntu
+func osFamilies(ctx context.Context) (map[int]string, error) {
+	list, err := listAllOS(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	families := make(map[int]string, len(list))
+	for _, o := range list {
+		families[o.ID] = o.Family
+	}
+	return families, nil
+}
+
+// sshUserForFamily returns the login user Vultr provisions for an OS family
+func sshUserForFamily(family string) string {
+	switch strings.ToLower(family) {
+	case "fedora-coreos", "flatcar":
+		return "core"
+	case "windows":
+		return "Administrator"
+	default:
+		return "root"
+	}
+}
+
+func sshCommandArgs(t sshTarget, extra []string, command string) []string {
+	args := append([]string{}, extra...)
+	args = append(args, t.destination())
+	if command != "" {
+		args = append(args, command)
+	}
+	return args
+}
+
+// sshInteractive hands the terminal over to ssh and returns its exit code
+func sshInteractive(t sshTarget, extra []string, command string) (int, error) {
+	c := exec.Command("ssh", sshCommandArgs(t, extra, command)...)
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+
+	if err := c.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode(), nil
+		}
+		return 0, err
+	}
+	return 0, nil
+}
+
+// sshParallel runs command on every target with at most limit connections
+// open at once and returns the number of targets it failed on.
+func sshParallel(targets []sshTarget, extra []string, command string, limit int) int {
+	if limit < 1 {
+		limit = 1
+	}
+
+	// BatchMode stops ssh from prompting for passwords or host keys, which
+	// would hang with several connections sharing one terminal.
+	extra = append([]string{"-o", "BatchMode=yes"}, extra...)
+
+	width := 0
+	for _, t := range targets {
+		if len(t.Name) > width {
+			width = len(t.Name)
+		}
+	}
+
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		failed int
+	)
+	sem := make(chan struct{}, limit)
+
+	for _, t := range targets {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(t sshTarget) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			prefix := fmt.Sprintf("%-*s | ", width, t.Name)
+			c := exec.Command("ssh", sshCommandArgs(t, extra, command)...)
+			stdout, _ := c.StdoutPipe()
+			stderr, _ := c.StderrPipe()
+
+			err := c.Start()
+			if err == nil {
+				var streams sync.WaitGroup
+				streams.Add(2)
+				go prefixLines(&streams, &mu, os.Stdout, stdout, prefix)
+				go prefixLines(&streams, &mu, os.Stderr, stderr, prefix)
+				streams.Wait()
+				err = c.Wait()
+			}
+
+			if err != nil {
+				mu.Lock()
+				failed++
+				fmt.Fprintf(os.Stderr, "%s%v\n", prefix, err)
+				mu.Unlock()
+			}
+		}(t)
+	}
+
+	wg.Wait()
+	return failed
+}
+
+func prefixLines(wg *sync.WaitGroup, mu *sync.Mutex, w io.Writer, r io.Reader, prefix string) {
+	defer wg.Done()
+
+	// A Reader rather than a Scanner, which gives up on long lines and
+	// would leave the child blocked on a full pipe
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadString('\n')
+		if line != "" {
+			mu.Lock()
+			fmt.Fprintf(w, "%s%s\n", prefix, strings.TrimSuffix(line, "\n"))
+			mu.Unlock()
+		}
+		if err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,341 @@
+// Copyright © 2019 The Vultr-cli Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/vultr/govultr/v2"
+)
+
+const (
+	sshConfigBlockBegin = "# BEGIN vultr-cli managed block"
+	sshConfigBlockEnd   = "# END vultr-cli managed block"
+)
+
+// SSHConfig represents the ssh-config command
+func SSHConfig() *cobra.Command {
+	sshConfigCmd := &cobra.Command{
+		Use:   "ssh-config",
+		Short: "write ssh_config entries for all instances and bare metal servers",
+		Long: `ssh-config writes a Host entry for every instance and bare metal server into a
+managed block of your ssh config. Re-running it only replaces that block, so
+hosts that no longer exist are removed and the rest of the file is untouched.
+
+Local public keys in ~/.ssh whose fingerprint matches one of the account's
+SSH keys are added as IdentityFile entries, together with IdentitiesOnly yes so
+ssh offers only those keys and not every key in the agent. The API does not
+report which keys each server was created with, so every matched key is
+written on every host; --identity picks the keys to write instead.`,
+		Run: sshConfigRun,
+	}
+
+	sshConfigCmd.Flags().StringP("file", "f", "", "(optional) ssh config file to update. Defaults to ~/.ssh/config")
+	sshConfigCmd.Flags().StringP("prefix", "", "", "(optional) prefix for every Host alias, e.g. vultr-")
+	sshConfigCmd.Flags().StringP("tag", "t", "", "(optional) only include servers with this tag. A trailing * matches by prefix")
+	sshConfigCmd.Flags().Bool("private", false, "(optional) use the internal IP of instances when one is assigned")
+	sshConfigCmd.Flags().Bool("dry-run", false, "(optional) print the managed block instead of writing it")
+	sshConfigCmd.Flags().StringArray("identity", nil, "(optional) private key to write as IdentityFile instead of the matched keys. Can be given more than once")
+
+	return sshConfigCmd
+}
+
+type sshConfigOptions struct {
+	private    bool
+	prefix     string
+	families   map[int]string
+	identities []string
+}
+
+func sshConfigRun(cmd *cobra.Command, args []string) {
+	file, _ := cmd.Flags().GetString("file")
+	prefix, _ := cmd.Flags().GetString("prefix")
+	tag, _ := cmd.Flags().GetString("tag")
+	private, _ := cmd.Flags().GetBool("private")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	identityFiles, _ := cmd.Flags().GetStringArray("identity")
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		fmt.Printf("error finding home directory : %v\n", err)
+		os.Exit(1)
+	}
+	if file == "" {
+		file = filepath.Join(home, ".ssh", "config")
+	}
+
+	ctx := context.Background()
+	hosts, err := listFleet(ctx)
+	if err != nil {
+		fmt.Printf("error getting servers : %v\n", err)
+		os.Exit(1)
+	}
+	hosts = filterHosts(hosts, tag)
+
+	families, err := osFamilies(ctx)
+	if err != nil {
+		fmt.Printf("error getting operating systems : %v\n", err)
+		os.Exit(1)
+	}
+
+	identities := identityFiles
+	if len(identities) == 0 {
+		keys, err := listAllSSHKeys(ctx)
+		if err != nil {
+			fmt.Printf("error getting ssh keys : %v\n", err)
+			os.Exit(1)
+		}
+
+		if identities, err = matchLocalIdentities(filepath.Join(home, ".ssh"), keys); err != nil {
+			fmt.Printf("error reading local ssh keys : %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	opts := &sshConfigOptions{private: private, prefix: prefix, families: families, identities: identities}
+	block := renderSSHConfig(hosts, opts)
+
+	if dryRun {
+		os.Stdout.Write(block)
+		return
+	}
+
+	current, err := ioutil.ReadFile(file)
+	if err != nil && !os.IsNotExist(err) {
+		fmt.Printf("error reading %s : %v\n", file, err)
+		os.Exit(1)
+	}
+
+	updated, err := replaceManagedBlock(current, block)
+	if err != nil {
+		fmt.Printf("error updating %s : %v\n", file, err)
+		os.Exit(1)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		fmt.Printf("error creating %s : %v\n", filepath.Dir(file), err)
+		os.Exit(1)
+	}
+
+	if err := writeFileAtomic(file, updated, 0600); err != nil {
+		fmt.Printf("error writing %s : %v\n", file, err)
+		os.Exit(1)
+	}
+
+	added, removed := diffHostAliases(sshConfigAliases(current), sshConfigAliases(updated))
+	fmt.Printf("Wrote %d hosts to %s (%d added, %d removed)\n", len(sshConfigAliases(updated)), file, added, removed)
+}
+
+// renderSSHConfig renders one Host entry per server. Aliases that collide
+// are disambiguated with the start of the server ID.
+func renderSSHConfig(hosts []fleetHost, opts *sshConfigOptions) []byte {
+	seen := map[string]int{}
+	for i := range hosts {
+		seen[hostAlias(&hosts[i])]++
+	}
+
+	var buf bytes.Buffer
+	for i := range hosts {
+		h := &hosts[i]
+		address := inventoryAddress(h, opts.private)
+		if address == "" {
+			continue
+		}
+
+		alias := hostAlias(h)
+		if seen[alias] > 1 && len(h.ID) >= 8 {
+			alias = fmt.Sprintf("%s-%s", alias, h.ID[:8])
+		}
+
+		fmt.Fprintf(&buf, "Host %s%s\n", opts.prefix, alias)
+		fmt.Fprintf(&buf, "    HostName %s\n", address)
+		if opts.families != nil {
+			fmt.Fprintf(&buf, "    User %s\n", sshUserForFamily(opts.families[h.OsID]))
+		}
+		for _, identity := range opts.identities {
+			fmt.Fprintf(&buf, "    IdentityFile %s\n", identity)
+		}
+		if len(opts.identities) > 0 {
+			buf.WriteString("    IdentitiesOnly yes\n")
+		}
+		buf.WriteString("\n")
+	}
+	return buf.Bytes()
+}
+
+// replaceManagedBlock swaps the managed block in an ssh config for block,
+// appending it when the file does not have one yet.
+func replaceManagedBlock(current, block []byte) ([]byte, error) {
+	managed := []byte(sshConfigBlockBegin + "\n")
+	managed = append(managed, block...)
+	managed = append(managed, []byte(sshConfigBlockEnd+"\n")...)
+
+	begin := bytes.Index(current, []byte(sshConfigBlockBegin))
+	end := bytes.Index(current, []byte(sshConfigBlockEnd))
+
+	switch {
+	case begin == -1 && end == -1:
+		out := append([]byte{}, current...)
+		if len(out) > 0 && !bytes.HasSuffix(out, []byte("\n")) {
+			out = append(out, '\n')
+		}
+		if len(out) > 0 {
+			out = append(out, '\n')
+		}
+		return append(out, managed...), nil
+	case begin == -1 || end == -1 || end < begin:
+		return nil, errors.New("the vultr-cli managed block markers are incomplete, please fix them by hand")
+	}
+
+	end += len(sshConfigBlockEnd)
+	if end < len(current) && current[end] == '\n' {
+		end++
+	}
+
+	out := append([]byte{}, current[:begin]...)
+	out = append(out, managed...)
+	return append(out, current[end:]...), nil
+}
+
+// sshConfigAliases returns the Host aliases inside the managed block
+func sshConfigAliases(config []byte) []string {
+	var aliases []string
+	inBlock := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(config))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == sshConfigBlockBegin:
+			inBlock = true
+		case line == sshConfigBlockEnd:
+			inBlock = false
+		case inBlock && strings.HasPrefix(line, "Host "):
+			aliases = append(aliases, strings.TrimSpace(strings.TrimPrefix(line, "Host ")))
+		}
+	}
+	return aliases
+}
+
+func diffHostAliases(before, after []string) (added, removed int) {
+	old := map[string]bool{}
+	for _, a := range before {
+		old[a] = true
+	}
+
+	current := map[string]bool{}
+	for _, a := range after {
+		current[a] = true
+		if !old[a] {
+			added++
+		}
+	}
+
+	for _, a := range before {
+		if !current[a] {
+			removed++
+		}
+	}
+	return added, removed
+}
+
+// listAllSSHKeys walks every page of the account's SSH keys
+func listAllSSHKeys(ctx context.Context) ([]govultr.SSHKey, error) {
+	var all []govultr.SSHKey
+	options := &govultr.ListOptions{PerPage: 100}
+	for {
+		keys, meta, err := client.SSHKey.List(ctx, options)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, keys...)
+
+		if meta == nil || meta.Links == nil || meta.Links.Next == "" {
+			return all, nil
+		}
+		options.Cursor = meta.Links.Next
+	}
+}
+
+// sshKeyFingerprint returns the OpenSSH SHA256 fingerprint of a public key
+// in authorized_keys format.
+func sshKeyFingerprint(authorizedKey string) (string, error) {
+	fields := strings.Fields(authorizedKey)
+	for i := 1; i < len(fields); i++ {
+		blob, err := base64.StdEncoding.DecodeString(fields[i])
+		if err != nil || len(blob) < 4 {
+			continue
+		}
+
+		// The key blob starts with its own length prefixed key type
+		n := int(binary.BigEndian.Uint32(blob))
+		if n > len(blob)-4 || string(blob[4:4+n]) != fields[i-1] {
+			continue
+		}
+
+		sum := sha256.Sum256(blob)
+		return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:]), nil
+	}
+	return "", errors.New("no key data found")
+}
+
+// matchLocalIdentities returns the private key paths in dir whose public key
+// is registered on the account.
+func matchLocalIdentities(dir string, keys []govultr.SSHKey) ([]string, error) {
+	registered := map[string]bool{}
+	for _, k := range keys {
+		if fp, err := sshKeyFingerprint(k.SSHKey); err == nil {
+			registered[fp] = true
+		}
+	}
+
+	pubs, err := filepath.Glob(filepath.Join(dir, "*.pub"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(pubs)
+
+	var identities []string
+	for _, pub := range pubs {
+		data, err := ioutil.ReadFile(pub)
+		if err != nil {
+			return nil, err
+		}
+
+		fp, err := sshKeyFingerprint(string(data))
+		if err != nil || !registered[fp] {
+			continue
+		}
+
+		private := strings.TrimSuffix(pub, ".pub")
+		if _, err := os.Stat(private); err == nil {
+			identities = append(identities, private)
+		}
+	}
+	return identities, nil
+}
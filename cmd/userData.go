@@ -0,0 +1,268 @@
+// Copyright © 2019 The Vultr-cli Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/vultr/govultr/v2"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// userDataSizeLimit is the largest base64 encoded user-data Vultr accepts
+	userDataSizeLimit = 64 * 1024
+
+	userDataBoundary = "MIMEBOUNDARY-vultr-cli"
+)
+
+// userDataPart is one file or inline string that goes into the user-data
+type userDataPart struct {
+	Name    string
+	Content string
+}
+
+// readUserDataParts reads the user-data files and renders each of them as a
+// Go template with the server's variables. Files that are cloud-init jinja
+// templates are left for cloud-init to render.
+func readUserDataParts(inline string, files []string, data *instanceTemplateData) ([]userDataPart, error) {
+	var parts []userDataPart
+	if inline != "" {
+		parts = append(parts, userDataPart{Name: "userdata", Content: inline})
+	}
+
+	for _, file := range files {
+		raw, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		content := string(raw)
+		if !strings.HasPrefix(content, "## template: jinja") && strings.Contains(content, "{{") {
+			tmpl, err := template.New(filepath.Base(file)).Option("missingkey=error").Parse(content)
+			if err != nil {
+				return nil, fmt.Errorf("error parsing %s : %v", file, err)
+			}
+
+			var buf bytes.Buffer
+			if err := tmpl.Execute(&buf, data); err != nil {
+				return nil, fmt.Errorf("error rendering %s : %v", file, err)
+			}
+			content = buf.String()
+		}
+
+		parts = append(parts, userDataPart{Name: filepath.Base(file), Content: content})
+	}
+
+	for _, p := range parts {
+		if err := validateCloudConfig(p); err != nil {
+			return nil, err
+		}
+	}
+
+	return parts, nil
+}
+
+// validateCloudConfig makes sure #cloud-config parts are a YAML mapping,
+// which is the first thing cloud-init checks and fails on silently.
+func validateCloudConfig(p userDataPart) error {
+	if !strings.HasPrefix(p.Content, "#cloud-config") {
+		return nil
+	}
+
+	var config map[string]interface{}
+	if err := yaml.Unmarshal([]byte(p.Content), &config); err != nil {
+		return fmt.Errorf("%s is not valid cloud-config : %v", p.Name, err)
+	}
+	return nil
+}
+
+// userDataContentType maps the first line of a part to its cloud-init MIME type
+func userDataContentType(content string) string {
+	switch {
+	case strings.HasPrefix(content, "#cloud-config-archive"):
+		return "text/cloud-config-archive"
+	case strings.HasPrefix(content, "#cloud-config"):
+		return "text/cloud-config"
+	case strings.HasPrefix(content, "## template: jinja"):
+		return "text/jinja2"
+	case strings.HasPrefix(content, "#!"):
+		return "text/x-shellscript"
+	case strings.HasPrefix(content, "#include"):
+		return "text/x-include-url"
+	case strings.HasPrefix(content, "#cloud-boothook"):
+		return "text/cloud-boothook"
+	case strings.HasPrefix(content, "#part-handler"):
+		return "text/part-handler"
+	default:
+		return "text/plain"
+	}
+}
+
+// buildUserData joins the parts into the raw user-data. A single part is
+// sent as is, several parts become a MIME multipart message.
+func buildUserData(parts []userDataPart) (string, error) {
+	switch len(parts) {
+	case 0:
+		return "", nil
+	case 1:
+		return parts[0].Content, nil
+	}
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	if err := w.SetBoundary(userDataBoundary); err != nil {
+		return "", err
+	}
+
+	for _, p := range parts {
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Type", fmt.Sprintf("%s; charset=\"utf-8\"", userDataContentType(p.Content)))
+		header.Set("MIME-Version", "1.0")
+		header.Set("Content-Transfer-Encoding", "7bit")
+		header.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", p.Name))
+
+		part, err := w.CreatePart(header)
+		if err != nil {
+			return "", err
+		}
+		if _, err := part.Write([]byte(p.Content)); err != nil {
+			return "", err
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "Content-Type: multipart/mixed; boundary=%q\r\n", userDataBoundary)
+	msg.WriteString("MIME-Version: 1.0\r\n\r\n")
+	msg.Write(body.Bytes())
+	return msg.String(), nil
+}
+
+// encodeUserData base64 encodes user-data and warns when it is larger than
+// the API accepts.
+func encodeUserData(raw string) string {
+	if raw == "" {
+		return ""
+	}
+
+	encoded := base64.StdEncoding.EncodeToString([]byte(raw))
+	if len(encoded) > userDataSizeLimit {
+		fmt.Fprintf(os.Stderr, "warning : user-data is %d bytes once base64 encoded, which is over the %d byte limit\n", len(encoded), userDataSizeLimit)
+	}
+	return encoded
+}
+
+// userDataFromFiles reads, renders and encodes user-data in one go
+func userDataFromFiles(inline string, files []string, data *instanceTemplateData) (string, error) {
+	parts, err := readUserDataParts(inline, files, data)
+	if err != nil {
+		return "", err
+	}
+
+	raw, err := buildUserData(parts)
+	if err != nil {
+		return "", err
+	}
+	return encodeUserData(raw), nil
+}
+
+// decodeUserData returns the plain text user-data
+func decodeUserData(u *govultr.UserData) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(u.Data)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding user-data : %v", err)
+	}
+	return data, nil
+}
+
+// diffUserData compares the current user-data with a local file
+func diffUserData(current []byte, file string) (string, error) {
+	local, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	return unifiedDiff("remote", file, string(current), string(local)), nil
+}
+
+// editUserData opens $VISUAL or $EDITOR on the user-data and returns the
+// edited content along with whether it was changed. Edits that do not
+// validate are kept in the temporary file, whose path is in the error.
+func editUserData(current []byte) ([]byte, bool, error) {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	tmp, err := ioutil.TempFile("", "vultr-user-data-*.txt")
+	if err != nil {
+		return nil, false, err
+	}
+	keep := false
+	defer func() {
+		if !keep {
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err := tmp.Write(current); err != nil {
+		tmp.Close()
+		return nil, false, err
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, false, err
+	}
+
+	// The editor may come with arguments, e.g. "code --wait"
+	args := strings.Fields(editor)
+	c := exec.Command(args[0], append(args[1:], tmp.Name())...)
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	if err := c.Run(); err != nil {
+		return nil, false, fmt.Errorf("error running %s : %v", editor, err)
+	}
+
+	edited, err := ioutil.ReadFile(tmp.Name())
+	if err != nil {
+		return nil, false, err
+	}
+
+	if bytes.Equal(edited, current) {
+		return edited, false, nil
+	}
+
+	if err := validateCloudConfig(userDataPart{Name: "user-data", Content: string(edited)}); err != nil {
+		keep = true
+		return nil, false, fmt.Errorf("%v. Your edits are kept in %s", err, tmp.Name())
+	}
+	return edited, true, nil
+}
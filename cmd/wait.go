@@ -0,0 +1,71 @@
+// Copyright © 2019 The Vultr-cli Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/vultr/govultr/v2"
+)
+
+const (
+	waitInterval = 5 * time.Second
+	waitTimeout  = 10 * time.Minute
+)
+
+// waitFor polls check until it reports done, returns an error or the timeout
+// passes.
+func waitFor(what string, timeout time.Duration, check func() (bool, error)) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		done, err := check()
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out after %s waiting for %s", timeout, what)
+		}
+		time.Sleep(waitInterval)
+	}
+}
+
+// instanceReady reports whether an instance is up and no longer being
+// installed, restored or resized.
+func instanceReady(i *govultr.Instance) bool {
+	if i.Status != "active" {
+		return false
+	}
+	return i.ServerStatus != "locked" && i.ServerStatus != "installingbooting"
+}
+
+// waitForInstance polls an instance until it is ready
+func waitForInstance(ctx context.Context, id string) (*govultr.Instance, error) {
+	var instance *govultr.Instance
+	err := waitFor(fmt.Sprintf("instance %s to become active", id), waitTimeout, func() (bool, error) {
+		i, err := client.Instance.Get(ctx, id)
+		if err != nil {
+			return false, err
+		}
+		instance = i
+		return instanceReady(i), nil
+	})
+	return instance, err
+}
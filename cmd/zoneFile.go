@@ -0,0 +1,602 @@
+// Copyright © 2019 The Vultr-cli Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/vultr/govultr/v2"
+	"github.com/vultr/vultr-cli/v2/cmd/printer"
+)
+
+// zoneDefaultTTL is the $TTL of exported zones, which records the API holds
+// without a TTL of their own inherit
+const zoneDefaultTTL = 3600
+
+// zoneToken is one field of a zone file line. Quoted strings keep their
+// escapes as written so TXT data goes through unchanged.
+type zoneToken struct {
+	text   string
+	quoted bool
+}
+
+// zoneLine is a logical line of a zone file, with parentheses joined
+type zoneLine struct {
+	number  int
+	text    string
+	tokens  []zoneToken
+	inherit bool // the line starts with a blank, so it uses the previous owner
+}
+
+// zoneFile is a parsed zone file in the shape of the Vultr API, with record
+// names relative to the domain.
+type zoneFile struct {
+	SOA     *govultr.Soa
+	Records []govultr.DomainRecord
+	Skipped []printer.ZoneSkipped
+}
+
+// tokenizeZone splits a zone file into logical lines, dropping comments and
+// joining lines inside parentheses as RFC 1035 section 5.1 describes.
+func tokenizeZone(data string) ([]zoneLine, error) {
+	var lines []zoneLine
+	var current zoneLine
+	var token strings.Builder
+	inToken, parens := false, 0
+	lineNo, startOfLine := 1, true
+	var raw strings.Builder
+
+	endToken := func() {
+		if inToken {
+			current.tokens = append(current.tokens, zoneToken{text: token.String()})
+			token.Reset()
+			inToken = false
+		}
+	}
+	endLine := func() {
+		endToken()
+		if len(current.tokens) > 0 {
+			current.text = strings.Join(strings.Fields(raw.String()), " ")
+			lines = append(lines, current)
+		}
+		current = zoneLine{}
+		raw.Reset()
+	}
+
+	runes := []rune(data)
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+
+		if startOfLine {
+			startOfLine = false
+			if parens == 0 {
+				current.number = lineNo
+				current.inherit = c == ' ' || c == '\t'
+			}
+		}
+
+		switch {
+		case c == '\n':
+			lineNo++
+			startOfLine = true
+			if parens == 0 {
+				endLine()
+			} else {
+				endToken()
+				raw.WriteRune(' ')
+			}
+			continue
+		case c == ';':
+			for i+1 < len(runes) && runes[i+1] != '\n' {
+				i++
+			}
+			continue
+		case c == '"':
+			endToken()
+			var s strings.Builder
+			closed := false
+			for i++; i < len(runes); i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					s.WriteRune(runes[i])
+					i++
+					s.WriteRune(runes[i])
+					continue
+				}
+				if runes[i] == '"' {
+					closed = true
+					break
+				}
+				if runes[i] == '\n' {
+					break
+				}
+				s.WriteRune(runes[i])
+			}
+			if !closed {
+				return nil, fmt.Errorf("line %d : unterminated quoted string", lineNo)
+			}
+			current.tokens = append(current.tokens, zoneToken{text: s.String(), quoted: true})
+			raw.WriteString(`"` + s.String() + `"`)
+			continue
+		case c == '(':
+			endToken()
+			parens++
+		case c == ')':
+			endToken()
+			if parens == 0 {
+				return nil, fmt.Errorf("line %d : unbalanced parentheses", lineNo)
+			}
+			parens--
+		case c == ' ' || c == '\t' || c == '\r':
+			endToken()
+		case c == '\\' && i+1 < len(runes):
+			token.WriteRune(c)
+			i++
+			token.WriteRune(runes[i])
+			inToken = true
+		default:
+			token.WriteRune(c)
+			inToken = true
+		}
+		raw.WriteRune(c)
+	}
+
+	if parens != 0 {
+		return nil, fmt.Errorf("line %d : unbalanced parentheses", lineNo)
+	}
+	endLine()
+
+	return lines, nil
+}
+
+// parseZoneTTL reads a TTL in seconds or in BIND units such as 1h30m
+func parseZoneTTL(s string) (int, bool) {
+	if n, err := strconv.Atoi(s); err == nil {
+		return n, n >= 0
+	}
+
+	total, num := 0, ""
+	for _, c := range strings.ToLower(s) {
+		if c >= '0' && c <= '9' {
+			num += string(c)
+			continue
+		}
+
+		n, err := strconv.Atoi(num)
+		if err != nil {
+			return 0, false
+		}
+		switch c {
+		case 's':
+		case 'm':
+			n *= 60
+		case 'h':
+			n *= 3600
+		case 'd':
+			n *= 86400
+		case 'w':
+			n *= 604800
+		default:
+			return 0, false
+		}
+		total += n
+		num = ""
+	}
+
+	return total, num == "" && total > 0
+}
+
+func isZoneClass(s string) bool {
+	switch strings.ToUpper(s) {
+	case "IN", "CH", "HS", "CS":
+		return true
+	}
+	return false
+}
+
+// qualifyName makes a zone file name absolute, without the trailing dot
+func qualifyName(name, origin string) string {
+	switch {
+	case name == "@":
+		return origin
+	case strings.HasSuffix(name, "."):
+		return strings.TrimSuffix(name, ".")
+	case origin == "":
+		return name
+	default:
+		return name + "." + origin
+	}
+}
+
+// relativeName returns name relative to domain as the API expects it, or
+// false when name is outside the domain.
+func relativeName(name, domain string) (string, bool) {
+	name, domain = canonicalName(name), canonicalName(domain)
+	switch {
+	case name == domain:
+		return "", true
+	case strings.HasSuffix(name, "."+domain):
+		return strings.TrimSuffix(name, "."+domain), true
+	default:
+		return "", false
+	}
+}
+
+// rnameToEmail turns the RNAME of an SOA record back into an email address.
+// The first unescaped dot separates the mailbox from the domain.
+func rnameToEmail(rname string) string {
+	if strings.Contains(rname, "@") {
+		return rname
+	}
+
+	for i := 0; i < len(rname); i++ {
+		switch rname[i] {
+		case '\\':
+			i++
+		case '.':
+			return strings.ReplaceAll(rname[:i], `\.`, ".") + "@" + rname[i+1:]
+		}
+	}
+	return rname
+}
+
+// parseZone reads an RFC 1035 zone file for domain into API records. Entries
+// Vultr DNS can not hold are returned in Skipped rather than failing the
+// whole file.
+func parseZone(data, domain string) (*zoneFile, error) {
+	lines, err := tokenizeZone(data)
+	if err != nil {
+		return nil, err
+	}
+
+	zf := &zoneFile{}
+	origin := canonicalName(domain)
+	owner, defaultTTL, lastTTL, soaMinimum := "", -1, -1, -1
+
+	skip := func(l zoneLine, format string, a ...interface{}) {
+		zf.Skipped = append(zf.Skipped, printer.ZoneSkipped{Line: l.number, Text: l.text, Reason: fmt.Sprintf(format, a...)})
+	}
+
+	for _, l := range lines {
+		tokens := l.tokens
+
+		if !tokens[0].quoted && strings.HasPrefix(tokens[0].text, "$") {
+			switch strings.ToUpper(tokens[0].text) {
+			case "$ORIGIN":
+				if len(tokens) < 2 {
+					return nil, fmt.Errorf("line %d : $ORIGIN needs a name", l.number)
+				}
+				origin = canonicalName(qualifyName(tokens[1].text, origin))
+			case "$TTL":
+				ttl, ok := 0, len(tokens) > 1
+				if ok {
+					ttl, ok = parseZoneTTL(tokens[1].text)
+				}
+				if !ok {
+					return nil, fmt.Errorf("line %d : invalid $TTL", l.number)
+				}
+				defaultTTL = ttl
+			default:
+				skip(l, "%s is not supported", tokens[0].text)
+			}
+			continue
+		}
+
+		if !l.inherit {
+			owner = canonicalName(qualifyName(tokens[0].text, origin))
+			tokens = tokens[1:]
+		} else if owner == "" {
+			return nil, fmt.Errorf("line %d : record without an owner name", l.number)
+		}
+
+		ttl, class := -1, "IN"
+		for len(tokens) > 0 && !tokens[0].quoted {
+			if n, ok := parseZoneTTL(tokens[0].text); ok && ttl < 0 {
+				ttl = n
+			} else if isZoneClass(tokens[0].text) {
+				class = strings.ToUpper(tokens[0].text)
+			} else {
+				break
+			}
+			tokens = tokens[1:]
+		}
+
+		if len(tokens) == 0 {
+			return nil, fmt.Errorf("line %d : missing record type", l.number)
+		}
+		rType := strings.ToUpper(tokens[0].text)
+		rdata := tokens[1:]
+
+		if rType == "SOA" && len(rdata) >= 7 {
+			if n, ok := parseZoneTTL(rdata[6].text); ok {
+				soaMinimum = n
+			}
+		}
+
+		// Without $TTL a record takes the TTL of the one before it and the
+		// first one the SOA minimum, as BIND does
+		switch {
+		case ttl >= 0:
+		case defaultTTL >= 0:
+			ttl = defaultTTL
+		case lastTTL >= 0:
+			ttl = lastTTL
+		case soaMinimum >= 0:
+			ttl = soaMinimum
+		default:
+			return nil, fmt.Errorf("line %d : record without a TTL and no $TTL or SOA before it", l.number)
+		}
+		lastTTL = ttl
+
+		if class != "IN" {
+			skip(l, "class %s is not supported", class)
+			continue
+		}
+
+		name, ok := relativeName(owner, domain)
+		if !ok {
+			skip(l, "%s is outside of %s", owner, domain)
+			continue
+		}
+
+		if rType == "SOA" {
+			if len(rdata) < 7 || name != "" {
+				skip(l, "invalid SOA record")
+				continue
+			}
+			zf.SOA = &govultr.Soa{
+				NSPrimary: qualifyName(rdata[0].text, origin),
+				Email:     rnameToEmail(qualifyName(rdata[1].text, origin)),
+			}
+			continue
+		}
+
+		record, reason := zoneRecord(rType, name, rdata, origin)
+		if reason != "" {
+			skip(l, "%s", reason)
+			continue
+		}
+		record.TTL = ttl
+		zf.Records = append(zf.Records, *record)
+	}
+
+	return zf, nil
+}
+
+// zoneRecord converts the rdata of one record into the API's data and
+// priority fields. The reason is set when Vultr DNS can not hold the record.
+func zoneRecord(rType, name string, rdata []zoneToken, origin string) (*govultr.DomainRecord, string) {
+	record := &govultr.DomainRecord{Type: rType, Name: name}
+
+	want := func(n int) bool { return len(rdata) == n }
+
+	switch rType {
+	case "A", "AAAA":
+		if !want(1) || net.ParseIP(rdata[0].text) == nil {
+			return nil, fmt.Sprintf("invalid %s record", rType)
+		}
+		if (rType == "A") != (net.ParseIP(rdata[0].text).To4() != nil) {
+			return nil, fmt.Sprintf("invalid %s record", rType)
+		}
+		record.Data = rdata[0].text
+	case "CNAME", "NS":
+		// Apex NS records are read like any other so an exported zone
+		// reads back whole; import decides which of them to keep
+		if !want(1) {
+			return nil, fmt.Sprintf("invalid %s record", rType)
+		}
+		record.Data = qualifyName(rdata[0].text, origin)
+	case "MX":
+		if !want(2) {
+			return nil, "invalid MX record"
+		}
+		priority, err := strconv.Atoi(rdata[0].text)
+		if err != nil {
+			return nil, "invalid MX priority"
+		}
+		record.Priority = priority
+		record.Data = qualifyName(rdata[1].text, origin)
+		if rdata[1].text == "." {
+			record.Data = "."
+		}
+	case "SRV":
+		if !want(4) {
+			return nil, "invalid SRV record"
+		}
+		priority, err := strconv.Atoi(rdata[0].text)
+		if err != nil {
+			return nil, "invalid SRV priority"
+		}
+		record.Priority = priority
+		record.Data = fmt.Sprintf("%s %s %s", rdata[1].text, rdata[2].text, qualifyName(rdata[3].text, origin))
+	case "TXT":
+		if len(rdata) == 0 {
+			return nil, "empty TXT record"
+		}
+		parts := make([]string, 0, len(rdata))
+		for _, t := range rdata {
+			parts = append(parts, t.text)
+		}
+		record.Data = formatTXT(parts)
+	case "CAA":
+		if !want(3) {
+			return nil, "invalid CAA record"
+		}
+		record.Data = fmt.Sprintf(`%s %s "%s"`, rdata[0].text, strings.ToLower(rdata[1].text), rdata[2].text)
+	case "SSHFP":
+		if len(rdata) < 3 {
+			return nil, "invalid SSHFP record"
+		}
+		var fp strings.Builder
+		for _, t := range rdata[2:] {
+			fp.WriteString(t.text)
+		}
+		record.Data = fmt.Sprintf("%s %s %s", rdata[0].text, rdata[1].text, strings.ToLower(fp.String()))
+	default:
+		return nil, fmt.Sprintf("%s records are not supported by Vultr DNS", rType)
+	}
+
+	data, err := normalizeRecordData(rType, record.Data)
+	if err != nil {
+		return nil, err.Error()
+	}
+	record.Data = data
+
+	return record, ""
+}
+
+// zoneRecordKey identifies a record by its content so files can be compared
+// with what is already in a domain.
+func zoneRecordKey(r govultr.DomainRecord) string {
+	priority := 0
+	if r.Type == "MX" || r.Type == "SRV" {
+		priority = r.Priority
+	}
+	return fmt.Sprintf("%s|%s|%d|%s", strings.ToUpper(r.Type), canonicalName(r.Name), priority, comparableData(r))
+}
+
+// comparableData is the record data in normalized form when it is valid, so
+// records entered in different ways compare equal
+func comparableData(r govultr.DomainRecord) string {
+	if data, err := normalizeRecordData(r.Type, r.Data); err == nil {
+		return data
+	}
+	return r.Data
+}
+
+// emailToRName writes an email address as the RNAME of an SOA record,
+// escaping dots in the mailbox.
+func emailToRName(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return absoluteName(email)
+	}
+	return absoluteName(strings.ReplaceAll(email[:at], ".", `\.`) + "." + email[at+1:])
+}
+
+// soaEmail checks the administrative contact of an SOA record, given as an
+// email address or as an RNAME, and returns it as an email address. The
+// address has to survive the conversion to an RNAME and back, which rules
+// out mailboxes with backslashes or empty labels.
+func soaEmail(value string) (string, error) {
+	email := strings.TrimSpace(value)
+	if !strings.Contains(email, "@") {
+		email = rnameToEmail(strings.TrimSuffix(email, "."))
+	}
+
+	at := strings.LastIndex(email, "@")
+	if at <= 0 || at == len(email)-1 {
+		return "", fmt.Errorf("%q is not an email address or RNAME", value)
+	}
+
+	mailbox, domain := email[:at], email[at+1:]
+	if strings.ContainsAny(mailbox, "@\\ \t") || strings.HasPrefix(mailbox, ".") || strings.HasSuffix(mailbox, ".") || strings.Contains(mailbox, "..") {
+		return "", fmt.Errorf("mailbox %q can not be written as an RNAME", mailbox)
+	}
+
+	domain, err := normalizeHostname(domain)
+	if err != nil || !strings.Contains(domain, ".") {
+		return "", fmt.Errorf("%q does not have a valid email domain", value)
+	}
+	email = mailbox + "@" + domain
+
+	if back := rnameToEmail(strings.TrimSuffix(emailToRName(email), ".")); back != email {
+		return "", fmt.Errorf("%q reads back from its RNAME as %q", email, back)
+	}
+	return email, nil
+}
+
+// absoluteName adds the trailing dot to a fully qualified name
+func absoluteName(name string) string {
+	if name == "" || strings.HasSuffix(name, ".") {
+		return name
+	}
+	return name + "."
+}
+
+// zoneRData writes the data and priority of an API record as zone file rdata
+func zoneRData(r govultr.DomainRecord) string {
+	switch r.Type {
+	case "CNAME", "NS":
+		return absoluteName(r.Data)
+	case "MX":
+		return fmt.Sprintf("%d %s", r.Priority, absoluteName(r.Data))
+	case "SRV":
+		fields := strings.Fields(r.Data)
+		if len(fields) > 0 {
+			fields[len(fields)-1] = absoluteName(fields[len(fields)-1])
+		}
+		return fmt.Sprintf("%d %s", r.Priority, strings.Join(fields, " "))
+	case "TXT":
+		return formatTXT(splitTXT(r.Data))
+	default:
+		return r.Data
+	}
+}
+
+// renderZone writes a domain as an RFC 1035 zone file that parseZone reads
+// back into the same records.
+func renderZone(domain string, soa *govultr.Soa, records []govultr.DomainRecord, serial string) string {
+	domain = canonicalName(domain)
+
+	sorted := make([]govultr.DomainRecord, len(records))
+	copy(sorted, records)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		if a.Priority != b.Priority {
+			return a.Priority < b.Priority
+		}
+		return a.Data < b.Data
+	})
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "$ORIGIN %s.\n", domain)
+	fmt.Fprintf(&buf, "$TTL %d\n", zoneDefaultTTL)
+
+	w := tabwriter.NewWriter(&buf, 0, 8, 1, '\t', 0)
+	if soa != nil {
+		// The API only exposes the primary nameserver and the email, so
+		// refresh, retry, expire and minimum are placeholders
+		fmt.Fprintf(w, "@\t\tIN\tSOA\t%s %s ( %s 7200 3600 1209600 %d ) ; refresh, retry, expire and minimum are placeholders\n", absoluteName(soa.NSPrimary), emailToRName(soa.Email), serial, zoneDefaultTTL)
+	}
+
+	for _, r := range sorted {
+		name := r.Name
+		if name == "" {
+			name = "@"
+		}
+
+		ttl := ""
+		if r.TTL > 0 {
+			ttl = strconv.Itoa(r.TTL)
+		}
+
+		fmt.Fprintf(w, "%s\t%s\tIN\t%s\t%s\n", name, ttl, r.Type, zoneRData(r))
+	}
+	w.Flush()
+
+	return buf.String()
+}
@@ -0,0 +1,287 @@
+// Copyright © 2019 The Vultr-cli Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/vultr/govultr/v2"
+)
+
+func TestParseZone(t *testing.T) {
+	const fingerprint = "abcdef0123456789abcdef0123456789abcdef0123456789abcdef0123456789"
+
+	tests := []struct {
+		name    string
+		zone    string
+		soa     *govultr.Soa
+		records []govultr.DomainRecord
+		skipped []int
+		err     string
+	}{
+		{
+			name: "records of every supported type",
+			zone: `$ORIGIN example.com.
+$TTL 1h
+@	IN	SOA	ns1.vultr.com. hostmaster\.admin.example.com. (
+		2026101801 ; serial
+		7200 3600 1209600 300 )
+@		IN	NS	ns1.vultr.com.
+@		IN	A	192.0.2.1
+www	300	IN	CNAME	@
+		IN	TXT	"v=spf1 -all" ; same owner as the line before
+mail		MX	10 mx1
+_sip._tcp	SRV	10 20 5060 sip.example.net.
+@		CAA	0 ISSUE "letsencrypt.org"
+host		SSHFP	4 2 ` + strings.ToUpper(fingerprint[:32]) + ` ` + fingerprint[32:] + `
+v6		AAAA	2001:db8::1
+`,
+			soa: &govultr.Soa{NSPrimary: "ns1.vultr.com", Email: "hostmaster.admin@example.com"},
+			records: []govultr.DomainRecord{
+				{Type: "NS", Name: "", Data: "ns1.vultr.com", TTL: 3600},
+				{Type: "A", Name: "", Data: "192.0.2.1", TTL: 3600},
+				{Type: "CNAME", Name: "www", Data: "example.com", TTL: 300},
+				{Type: "TXT", Name: "www", Data: `"v=spf1 -all"`, TTL: 3600},
+				{Type: "MX", Name: "mail", Data: "mx1.example.com", Priority: 10, TTL: 3600},
+				{Type: "SRV", Name: "_sip._tcp", Data: "20 5060 sip.example.net", Priority: 10, TTL: 3600},
+				{Type: "CAA", Name: "", Data: `0 issue "letsencrypt.org"`, TTL: 3600},
+				{Type: "SSHFP", Name: "host", Data: "4 2 " + fingerprint, TTL: 3600},
+				{Type: "AAAA", Name: "v6", Data: "2001:db8::1", TTL: 3600},
+			},
+		},
+		{
+			name: "entries Vultr DNS can not hold are skipped",
+			zone: `$TTL 300
+other.org.	A	192.0.2.9
+@		CH	TXT	"chaos"
+loc		LOC	52 22 23 N 4 53 32 E -2m
+$INCLUDE other.zone
+bad		A	2001:db8::1
+www		A	192.0.2.1
+`,
+			records: []govultr.DomainRecord{
+				{Type: "A", Name: "www", Data: "192.0.2.1", TTL: 300},
+			},
+			skipped: []int{2, 3, 4, 5, 6},
+		},
+		{
+			name: "TTL of the record before without $TTL",
+			zone: "www 60 IN A 192.0.2.1\nmail IN A 192.0.2.2\n",
+			records: []govultr.DomainRecord{
+				{Type: "A", Name: "www", Data: "192.0.2.1", TTL: 60},
+				{Type: "A", Name: "mail", Data: "192.0.2.2", TTL: 60},
+			},
+		},
+		{
+			name: "SOA minimum without $TTL",
+			zone: "@ IN SOA ns1.vultr.com. admin.example.com. ( 1 7200 3600 1209600 600 )\nwww IN A 192.0.2.1\n",
+			soa:  &govultr.Soa{NSPrimary: "ns1.vultr.com", Email: "admin@example.com"},
+			records: []govultr.DomainRecord{
+				{Type: "A", Name: "www", Data: "192.0.2.1", TTL: 600},
+			},
+		},
+		{
+			name: "null MX and relative names under a new origin",
+			zone: "$TTL 300\n$ORIGIN sub.example.com.\n@ MX 0 .\nwww CNAME host\n",
+			records: []govultr.DomainRecord{
+				{Type: "MX", Name: "sub", Data: ".", TTL: 300},
+				{Type: "CNAME", Name: "www.sub", Data: "host.sub.example.com", TTL: 300},
+			},
+		},
+		{
+			name: "no TTL at all",
+			zone: "www IN A 192.0.2.1\n",
+			err:  "line 1 : record without a TTL",
+		},
+		{
+			name: "invalid $TTL",
+			zone: "$TTL forever\n",
+			err:  "line 1 : invalid $TTL",
+		},
+		{
+			name: "unbalanced parentheses",
+			zone: "@ IN SOA a. b. ( 1 2 3 4 5\n",
+			err:  "unbalanced parentheses",
+		},
+		{
+			name: "continuation line first",
+			zone: "\tIN A 192.0.2.1\n",
+			err:  "line 1 : record without an owner name",
+		},
+		{
+			name: "missing type",
+			zone: "$TTL 60\nwww IN\n",
+			err:  "line 2 : missing record type",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			zf, err := parseZone(tt.zone, "example.com")
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("parseZone() error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseZone() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(zf.SOA, tt.soa) {
+				t.Errorf("SOA = %+v, want %+v", zf.SOA, tt.soa)
+			}
+			if !reflect.DeepEqual(zf.Records, tt.records) {
+				t.Errorf("records = %+v, want %+v", zf.Records, tt.records)
+			}
+
+			var skipped []int
+			for _, s := range zf.Skipped {
+				skipped = append(skipped, s.Line)
+			}
+			if !reflect.DeepEqual(skipped, tt.skipped) {
+				t.Errorf("skipped lines = %v, want %v (%+v)", skipped, tt.skipped, zf.Skipped)
+			}
+		})
+	}
+}
+
+func TestRenderZone(t *testing.T) {
+	soa := &govultr.Soa{NSPrimary: "ns1.vultr.com", Email: "host.master@example.com"}
+	records := []govultr.DomainRecord{
+		{Type: "MX", Name: "", Data: "mx1.example.com", Priority: 10, TTL: 300},
+		{Type: "A", Name: "www", Data: "192.0.2.1"},
+		{Type: "TXT", Name: "", Data: `"v=spf1 -all"`, TTL: 300},
+	}
+
+	want := "$ORIGIN example.com.\n" +
+		"$TTL 3600\n" +
+		"@\t\tIN\tSOA\tns1.vultr.com. host\\.master.example.com. ( 2026101801 7200 3600 1209600 3600 ) ; refresh, retry, expire and minimum are placeholders\n" +
+		"@\t300\tIN\tMX\t10 mx1.example.com.\n" +
+		"@\t300\tIN\tTXT\t\"v=spf1 -all\"\n" +
+		"www\t\tIN\tA\t192.0.2.1\n"
+
+	if got := renderZone("Example.com.", soa, records, "2026101801"); got != want {
+		t.Errorf("renderZone() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestRenderZoneReadsBack(t *testing.T) {
+	tests := []struct {
+		name   string
+		record govultr.DomainRecord
+		want   govultr.DomainRecord
+	}{
+		{
+			name:   "A without a TTL gets $TTL",
+			record: govultr.DomainRecord{Type: "A", Name: "www", Data: "192.0.2.1"},
+			want:   govultr.DomainRecord{Type: "A", Name: "www", Data: "192.0.2.1", TTL: zoneDefaultTTL},
+		},
+		{
+			name:   "AAAA",
+			record: govultr.DomainRecord{Type: "AAAA", Name: "", Data: "2001:db8::1", TTL: 60},
+		},
+		{
+			name:   "CNAME",
+			record: govultr.DomainRecord{Type: "CNAME", Name: "www", Data: "example.com", TTL: 300},
+		},
+		{
+			name:   "NS of a subdomain",
+			record: govultr.DomainRecord{Type: "NS", Name: "sub", Data: "ns1.example.net", TTL: 300},
+		},
+		{
+			name:   "MX",
+			record: govultr.DomainRecord{Type: "MX", Name: "", Data: "mx1.example.com", Priority: 10, TTL: 300},
+		},
+		{
+			name:   "null MX",
+			record: govultr.DomainRecord{Type: "MX", Name: "", Data: ".", TTL: 300},
+		},
+		{
+			name:   "SRV",
+			record: govultr.DomainRecord{Type: "SRV", Name: "_sip._tcp", Data: "20 5060 sip.example.net", Priority: 10, TTL: 300},
+		},
+		{
+			name:   "TXT with quotes and spaces",
+			record: govultr.DomainRecord{Type: "TXT", Name: "_dmarc", Data: `"v=DMARC1; p=none; rua=mailto:a@example.com"`, TTL: 300},
+		},
+		{
+			name:   "CAA",
+			record: govultr.DomainRecord{Type: "CAA", Name: "", Data: `0 issue "letsencrypt.org"`, TTL: 300},
+		},
+		{
+			name:   "wildcard",
+			record: govultr.DomainRecord{Type: "A", Name: "*.dev", Data: "192.0.2.2", TTL: 300},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := tt.want
+			if want.Type == "" {
+				want = tt.record
+			}
+
+			text := renderZone("example.com", nil, []govultr.DomainRecord{tt.record}, "1")
+			zf, err := parseZone(text, "example.com")
+			if err != nil {
+				t.Fatalf("parseZone() error = %v\n%s", err, text)
+			}
+			if len(zf.Skipped) > 0 {
+				t.Fatalf("parseZone() skipped %+v\n%s", zf.Skipped, text)
+			}
+			if len(zf.Records) != 1 || !reflect.DeepEqual(zf.Records[0], want) {
+				t.Errorf("read back %+v, want %+v\n%s", zf.Records, want, text)
+			}
+		})
+	}
+}
+
+func TestSOAEmail(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+		err   string
+	}{
+		{"admin@example.com", "admin@example.com", ""},
+		{" Admin@Example.COM. ", "Admin@example.com", ""},
+		{"john.doe@example.com", "john.doe@example.com", ""},
+		{"hostmaster.example.com.", "hostmaster@example.com", ""},
+		{`john\.doe.example.com`, "john.doe@example.com", ""},
+		{"admin", "", "not an email address or RNAME"},
+		{"@example.com", "", "not an email address or RNAME"},
+		{"admin@", "", "not an email address or RNAME"},
+		{"admin@localhost", "", "valid email domain"},
+		{"admin@bad_host!.com", "", "valid email domain"},
+		{"a b@example.com", "", "can not be written as an RNAME"},
+		{".a@example.com", "", "can not be written as an RNAME"},
+		{"a..b@example.com", "", "can not be written as an RNAME"},
+	}
+
+	for _, tt := range tests {
+		got, err := soaEmail(tt.value)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("soaEmail(%q) error = %v, want %q", tt.value, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("soaEmail(%q) = %q, %v, want %q", tt.value, got, err, tt.want)
+		}
+	}
+}
@@ -7,4 +7,5 @@ require (
 	github.com/spf13/viper v1.10.0
 	github.com/vultr/govultr/v2 v2.12.0
 	golang.org/x/oauth2 v0.0.0-20211104180415-d3ed0bb246c8
+	gopkg.in/yaml.v2 v2.4.0
 )